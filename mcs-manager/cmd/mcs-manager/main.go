@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/log"
 
 	"obsidian/internal/api"
+	"obsidian/internal/audit"
+	"obsidian/internal/auth"
 	"obsidian/internal/manager"
 	"obsidian/internal/store"
 	"obsidian/pkg/events"
@@ -16,10 +24,23 @@ import (
 type BootConfig struct {
 	Root string `json:"root"`
 	Bind string `json:"bind"`
+	// ShutdownTimeoutSec bounds how long servers get to stop on SIGINT/SIGTERM
+	ShutdownTimeoutSec int `json:"shutdownTimeoutSec"`
+	// ProfileAPI is the base URL of the Mojang compatible profile API used to
+	// resolve player names, ProfileCacheHours how long results are cached
+	ProfileAPI        string `json:"profileApi"`
+	ProfileCacheHours int    `json:"profileCacheHours"`
+	// AllowedOrigins may call the API from a browser, by default the UI's dev server
+	AllowedOrigins []string `json:"allowedOrigins"`
 }
 
 func main() {
-	cfg := BootConfig{Root: defaultRoot(), Bind: ":8484"}
+	cfg := BootConfig{
+		Root:               defaultRoot(),
+		Bind:               ":8484",
+		ShutdownTimeoutSec: 90,
+		AllowedOrigins:     []string{"http://localhost:5173", "http://127.0.0.1:5173"},
+	}
 	if env := os.Getenv("MCS_CONFIG"); env != "" {
 		log.Info("loading config from env", "path", env)
 		b, err := os.ReadFile(env)
@@ -38,10 +59,58 @@ func main() {
 	if err != nil {
 		log.Fatal("failed to initialize manager", "err", err)
 	}
+	mgr.SetProfileAPI(cfg.ProfileAPI, time.Duration(cfg.ProfileCacheHours)*time.Hour)
+
+	users, err := auth.Open(cfg.Root)
+	if err != nil {
+		log.Fatal("failed to load users", "err", err)
+	}
+	if secret, err := users.Bootstrap(); err != nil {
+		log.Fatal("failed to create initial admin user", "err", err)
+	} else if secret != "" {
+		file := filepath.Join(cfg.Root, "admin-token")
+		if err := os.WriteFile(file, []byte(secret+"\n"), 0o600); err != nil {
+			log.Fatal("failed to write initial admin token", "err", err)
+		}
+		log.Warn("created initial admin user, token written to file", "path", file)
+	}
+
+	auditLog, err := audit.Open(cfg.Root)
+	if err != nil {
+		log.Fatal("failed to open audit log", "err", err)
+	}
 
 	log.Info("starting HTTP API server", "bind", cfg.Bind)
-	apiSrv := api.NewHTTP(cfg.Bind, mgr, bus)
-	log.Fatal(apiSrv.ListenAndServe())
+	apiSrv := api.NewHTTP(cfg.Bind, cfg.AllowedOrigins, mgr, bus, users, auditLog)
+	srvErr := make(chan error, 1)
+	go func() { srvErr <- apiSrv.ListenAndServe() }()
+	go mgr.AutoStart()
+
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-srvErr:
+		log.Fatal(err)
+	case s := <-sig:
+		log.Info("received signal, shutting down", "signal", s, "timeout", cfg.ShutdownTimeoutSec)
+	}
+	go func() {
+		<-sig
+		log.Warn("received second signal, exiting immediately")
+		os.Exit(1)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSec)*time.Second)
+	defer cancel()
+	// The manager goes first: it cancels starts still running their pre-start
+	// hook, which the HTTP server would otherwise wait for
+	if err := mgr.Shutdown(ctx); err != nil {
+		log.Warn("not all servers stopped gracefully", "err", err)
+	}
+	if err := apiSrv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Warn("failed to shut down HTTP API server", "err", err)
+	}
+	log.Info("shutdown complete")
 }
 
 func defaultRoot() string {
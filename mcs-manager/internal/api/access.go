@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/audit"
+	"obsidian/internal/manager"
+)
+
+// handleAccess serves /servers/{id}/{list} and /servers/{id}/{list}/{key} for
+// the whitelist, ops, banned-players and banned-ips lists. key is a player
+// name, UUID or IP address.
+func (a *API) handleAccess(w http.ResponseWriter, r *http.Request, s *manager.Server, id string, list manager.AccessList, parts []string) {
+	var err error
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		entries, err := s.AccessEntries(list)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		writeJSON(w, entries)
+		return
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var req manager.AccessRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		log.Info("API request to update access list", "id", id, "list", list, "name", req.Name, "ip", req.IP)
+		if err = s.AddAccess(list, req); err == nil {
+			a.record(r, audit.Entry{Action: "access.add", ServerID: id, Details: accessDetails(list, req)})
+		}
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		log.Info("API request to update access list", "id", id, "list", list, "remove", parts[2])
+		if err = s.RemoveAccess(list, parts[2]); err == nil {
+			a.record(r, audit.Entry{Action: "access.remove", ServerID: id, Details: map[string]any{"list": list, "key": parts[2]}})
+		}
+	default:
+		w.WriteHeader(405)
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, manager.ErrAccessEntryNotFound):
+			http.Error(w, err.Error(), 404)
+		case errors.Is(err, manager.ErrServerStopping), errors.Is(err, manager.ErrNotRunning):
+			http.Error(w, err.Error(), 409)
+		default:
+			http.Error(w, err.Error(), 400)
+		}
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func accessDetails(list manager.AccessList, req manager.AccessRequest) map[string]any {
+	d := map[string]any{"list": list}
+	if req.Name != "" {
+		d["name"] = req.Name
+	}
+	if req.IP != "" {
+		d["ip"] = req.IP
+	}
+	if req.Level != 0 {
+		d["level"] = req.Level
+	}
+	if req.Reason != "" {
+		d["reason"] = req.Reason
+	}
+	if req.Expires != "" {
+		d["expires"] = req.Expires
+	}
+	return d
+}
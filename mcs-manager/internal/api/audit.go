@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"obsidian/internal/audit"
+	"obsidian/internal/auth"
+)
+
+// record adds a successful mutating request to the audit log.
+func (a *API) record(r *http.Request, e audit.Entry) {
+	if id := identity(r); id != nil {
+		e.ActorID, e.Actor = id.User.ID, id.User.Name
+	}
+	e.Remote = r.RemoteAddr
+	a.audit.Record(e)
+}
+
+// handleAudit serves GET /audit?server=&actor=&action=&since=&until=&limit=.
+// since and until are RFC 3339 timestamps, limit is at most audit.MaxLimit.
+// Admins of a single server may read that server's entries.
+func (a *API) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	q := r.URL.Query()
+	flt := audit.Filter{ServerID: q.Get("server"), Actor: q.Get("actor"), Action: q.Get("action")}
+	if !allow(w, r, flt.ServerID, auth.RoleAdmin) {
+		return
+	}
+	var err error
+	if v := q.Get("since"); v != "" {
+		if flt.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid since: "+err.Error(), 400)
+			return
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if flt.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid until: "+err.Error(), 400)
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if flt.Limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "invalid limit", 400)
+			return
+		}
+		if flt.Limit > audit.MaxLimit {
+			http.Error(w, "limit must be at most "+strconv.Itoa(audit.MaxLimit), 400)
+			return
+		}
+	}
+	entries, err := a.audit.Query(flt)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	writeJSON(w, entries)
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/audit"
+	"obsidian/internal/auth"
+)
+
+type identityKey struct{}
+
+// withAuth rejects requests without a valid bearer token. EventSource cannot set
+// headers, so /events also accepts the token as ?token=.
+func (a *API) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && r.URL.Path == "/events" {
+			secret = r.URL.Query().Get("token")
+		}
+		id, err := a.auth.Authenticate(strings.TrimSpace(secret))
+		if err != nil {
+			log.Debug("rejected unauthenticated request", "path", r.URL.Path, "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="obsidian"`)
+			http.Error(w, err.Error(), 401)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+func identity(r *http.Request) *auth.Identity {
+	id, _ := r.Context().Value(identityKey{}).(*auth.Identity)
+	return id
+}
+
+// allow writes 403 and returns false unless the caller has at least need on
+// serverID ("" for global permissions).
+func allow(w http.ResponseWriter, r *http.Request, serverID string, need auth.Role) bool {
+	id := identity(r)
+	if id != nil && id.Can(serverID, need) {
+		return true
+	}
+	log.Warn("forbidden request", "path", r.URL.Path, "method", r.Method, "server", serverID, "need", need)
+	http.Error(w, "forbidden", 403)
+	return false
+}
+
+// serverRole is the role needed for a request below /servers/{id}.
+func serverRole(r *http.Request, parts []string) auth.Role {
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if len(parts) == 1 {
+		if read {
+			return auth.RoleViewer
+		}
+		return auth.RoleAdmin
+	}
+	switch parts[1] {
+	case "logs", "players", "sessions", "stats":
+		return auth.RoleViewer
+	case "start", "stop", "kill", "restart", "cmd", "exec", "schedules":
+		if read {
+			return auth.RoleViewer
+		}
+		return auth.RoleOperator
+	case "backups":
+		// Creating a backup is harmless, restoring one replaces the world
+		if read {
+			return auth.RoleViewer
+		}
+		if len(parts) == 2 {
+			return auth.RoleOperator
+		}
+		return auth.RoleAdmin
+	case "properties", "ops":
+		if read {
+			return auth.RoleViewer
+		}
+		return auth.RoleAdmin
+	case "whitelist", "banned-players", "banned-ips":
+		if read {
+			return auth.RoleViewer
+		}
+		return auth.RoleOperator
+	}
+	return auth.RoleAdmin
+}
+
+// handleMe returns the caller's user and token.
+func (a *API) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	id := identity(r)
+	writeJSON(w, map[string]any{"user": id.User, "token": id.Token})
+}
+
+// handleUsers serves /users, /users/{id} and /users/{id}/tokens[/{tokenId}].
+// Only admins manage users; everyone may manage their own tokens.
+func (a *API) handleUsers(w http.ResponseWriter, r *http.Request) {
+	tail := strings.Trim(strings.TrimPrefix(r.URL.Path, "/users"), "/")
+	var parts []string
+	if tail != "" {
+		parts = strings.Split(tail, "/")
+	}
+	self := len(parts) > 0 && parts[0] == identity(r).User.ID
+	if len(parts) < 2 || !self {
+		if !allow(w, r, "", auth.RoleAdmin) {
+			return
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, a.auth.ListUsers())
+		case http.MethodPost:
+			var u auth.User
+			if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			created, err := a.auth.CreateUser(u)
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			a.record(r, audit.Entry{Action: "user.create", Details: map[string]any{"user": created.ID, "name": created.Name, "role": created.Role}})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			writeJSON(w, created)
+		default:
+			w.WriteHeader(405)
+		}
+	case 1:
+		id := parts[0]
+		switch r.Method {
+		case http.MethodGet:
+			u, err := a.auth.GetUser(id)
+			if err != nil {
+				http.Error(w, err.Error(), 404)
+				return
+			}
+			writeJSON(w, u)
+		case http.MethodPut:
+			var u auth.User
+			if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			updated, err := a.auth.UpdateUser(id, u)
+			if err != nil {
+				http.Error(w, err.Error(), authStatus(err))
+				return
+			}
+			a.record(r, audit.Entry{Action: "user.update", Details: map[string]any{"user": id, "role": updated.Role, "servers": updated.Servers}})
+			writeJSON(w, updated)
+		case http.MethodDelete:
+			if err := a.auth.DeleteUser(id); err != nil {
+				http.Error(w, err.Error(), authStatus(err))
+				return
+			}
+			a.record(r, audit.Entry{Action: "user.delete", Details: map[string]any{"user": id}})
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(405)
+		}
+	default:
+		if parts[1] != "tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		a.handleTokens(w, r, parts[0], parts)
+	}
+}
+
+func (a *API) handleTokens(w http.ResponseWriter, r *http.Request, userID string, parts []string) {
+	if len(parts) == 3 {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(405)
+			return
+		}
+		if err := a.auth.RevokeToken(userID, parts[2]); err != nil {
+			http.Error(w, err.Error(), authStatus(err))
+			return
+		}
+		a.record(r, audit.Entry{Action: "token.revoke", Details: map[string]any{"user": userID, "token": parts[2]}})
+		w.WriteHeader(204)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, a.auth.ListTokens(userID))
+	case http.MethodPost:
+		var req struct {
+			Name        string    `json:"name"`
+			Role        auth.Role `json:"role"`
+			ExpiresDays int       `json:"expiresDays"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		// A token may only hand out what it can do itself, and a capped
+		// token must not create an uncapped one
+		caller := identity(r)
+		if (req.Role == "" && caller.Token.Role != "") || (req.Role != "" && !caller.RoleFor("").Allows(req.Role)) {
+			log.Warn("refused token with more rights than the caller", "user", userID, "role", req.Role, "caller", caller.User.ID)
+			http.Error(w, "token role exceeds the caller's role", 403)
+			return
+		}
+		t := auth.Token{Name: req.Name, Role: req.Role}
+		if req.ExpiresDays > 0 {
+			exp := time.Now().AddDate(0, 0, req.ExpiresDays)
+			t.ExpiresAt = &exp
+		}
+		created, secret, err := a.auth.CreateToken(userID, t)
+		if err != nil {
+			http.Error(w, err.Error(), authStatus(err))
+			return
+		}
+		a.record(r, audit.Entry{Action: "token.create", Details: map[string]any{"user": userID, "token": created.ID, "role": created.Role}})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(201)
+		writeJSON(w, map[string]any{"token": created, "secret": secret})
+	default:
+		w.WriteHeader(405)
+	}
+}
+
+func authStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound), errors.Is(err, auth.ErrTokenNotFound):
+		return 404
+	case errors.Is(err, auth.ErrLastAdmin):
+		return 409
+	}
+	return 400
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/audit"
+	"obsidian/internal/auth"
+	"obsidian/internal/manager"
+)
+
+// handleBackups serves /servers/{id}/backups and /servers/{id}/backups/{backupId}/restore
+func (a *API) handleBackups(w http.ResponseWriter, r *http.Request, id string, parts []string) {
+	switch len(parts) {
+	case 2:
+		switch r.Method {
+		case http.MethodGet:
+			log.Debug("listing backups", "id", id)
+			list, err := a.mgr.ListBackups(id)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			writeJSON(w, list)
+		case http.MethodPost:
+			log.Info("API request to create backup", "id", id)
+			b, err := a.mgr.CreateBackup(id)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			a.record(r, audit.Entry{Action: "backup.create", ServerID: id, Details: map[string]any{"backup": b.ID}})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			writeJSON(w, b)
+		default:
+			w.WriteHeader(405)
+		}
+	case 4:
+		if parts[3] != "restore" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(405)
+			return
+		}
+		log.Info("API request to restore backup", "id", id, "backup", parts[2])
+		if err := a.mgr.RestoreBackup(id, parts[2]); err != nil {
+			if errors.Is(err, manager.ErrBackupNotFound) {
+				http.Error(w, err.Error(), 404)
+				return
+			}
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		a.record(r, audit.Entry{Action: "backup.restore", ServerID: id, Details: map[string]any{"backup": parts[2]}})
+		w.WriteHeader(204)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleDeletedBackups serves /backups, the backups left behind by deleted
+// servers, and /backups/{id}/{backupId}/restore, which recreates the server.
+func (a *API) handleDeletedBackups(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, "", auth.RoleAdmin) {
+		return
+	}
+	tail := strings.Trim(strings.TrimPrefix(r.URL.Path, "/backups"), "/")
+	if tail == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(405)
+			return
+		}
+		list, err := a.mgr.ListDeleted()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		writeJSON(w, list)
+		return
+	}
+	parts := strings.Split(tail, "/")
+	if len(parts) != 3 || parts[2] != "restore" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(405)
+		return
+	}
+	id := parts[0]
+	log.Info("API request to restore deleted server", "id", id, "backup", parts[1])
+	s, err := a.mgr.RestoreDeleted(id, parts[1])
+	if err != nil {
+		if errors.Is(err, manager.ErrBackupNotFound) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	info := s.Info()
+	a.record(r, audit.Entry{Action: "backup.restore", ServerID: id, Details: map[string]any{"backup": parts[1], "deleted": true, "name": info.Config.Name}})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	writeJSON(w, info)
+}
@@ -1,44 +1,78 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/log"
 
+	"obsidian/internal/audit"
+	"obsidian/internal/auth"
 	"obsidian/internal/manager"
+	"obsidian/internal/rcon"
 	"obsidian/internal/resolver"
 	"obsidian/internal/util"
 	"obsidian/pkg/events"
 )
 
 type API struct {
-	mgr *manager.Manager
-	bus *events.Bus
+	mgr   *manager.Manager
+	bus   *events.Bus
+	auth  *auth.Store
+	audit *audit.Log
 }
 
-func NewHTTP(bind string, mgr *manager.Manager, bus *events.Bus) *http.Server {
-	api := &API{mgr: mgr, bus: bus}
+// NewHTTP builds the API server. origins are the browser origins allowed to
+// call it cross-origin, e.g. the UI's dev server.
+func NewHTTP(bind string, origins []string, mgr *manager.Manager, bus *events.Bus, users *auth.Store, auditLog *audit.Log) *http.Server {
+	api := &API{mgr: mgr, bus: bus, auth: users, audit: auditLog}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte("ok")) })
 	mux.HandleFunc("/servers", api.handleServers)
 	mux.HandleFunc("/servers/", api.handleServerByID)
 	mux.HandleFunc("/events", api.handleSSE)
 	mux.HandleFunc("/versions", handleVersions)
-	return &http.Server{Addr: bind, Handler: withCORS(mux)}
+	mux.HandleFunc("/users", api.handleUsers)
+	mux.HandleFunc("/users/", api.handleUsers)
+	mux.HandleFunc("/auth/me", api.handleMe)
+	mux.HandleFunc("/audit", api.handleAudit)
+	mux.HandleFunc("/watches", api.handleWatches)
+	mux.HandleFunc("/watches/", api.handleWatches)
+	mux.HandleFunc("/runtimes", api.handleRuntimes)
+	mux.HandleFunc("/backups", api.handleDeletedBackups)
+	mux.HandleFunc("/backups/", api.handleDeletedBackups)
+	// Cancelling the base context on Shutdown ends long-lived /events streams
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := &http.Server{Addr: bind, Handler: withCORS(origins, api.withAuth(mux)), BaseContext: func(net.Listener) context.Context { return ctx }}
+	srv.RegisterOnShutdown(cancel)
+	return srv
 }
 
 func (a *API) handleServers(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		log.Debug("listing servers")
-		writeJSON(w, a.mgr.List())
+		id := identity(r)
+		list := []manager.ServerInfo{}
+		for _, info := range a.mgr.List() {
+			if id.Can(info.Config.ID, auth.RoleViewer) {
+				list = append(list, info)
+			}
+		}
+		writeJSON(w, list)
 	case http.MethodPost:
+		if !allow(w, r, "", auth.RoleAdmin) {
+			return
+		}
 		log.Info("creating new server from API request")
 		var cfg manager.ServerConfig
 		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
@@ -52,7 +86,13 @@ func (a *API) handleServers(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), 400)
 			return
 		}
-		writeJSON(w, s.Info())
+		info := s.Info()
+		a.record(r, audit.Entry{Action: "server.create", ServerID: info.Config.ID, Details: map[string]any{
+			"name":    info.Config.Name,
+			"type":    info.Config.Type,
+			"version": info.Config.Version,
+		}})
+		writeJSON(w, info)
 	default:
 		w.WriteHeader(405)
 	}
@@ -67,6 +107,9 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := parts[0]
+	if !allow(w, r, id, serverRole(r, parts)) {
+		return
+	}
 
 	s, ok := a.mgr.Get(id)
 	if !ok {
@@ -81,10 +124,15 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, s.Info())
 			return
 		case http.MethodDelete:
-			if err := a.mgr.Delete(id); err != nil {
+			name := s.Info().Config.Name
+			// ?force=1 deletes without the final backup, e.g. when the disk is full
+			force := r.URL.Query().Get("force")
+			skipBackup := force == "1" || force == "true"
+			if err := a.mgr.Delete(id, skipBackup); err != nil {
 				http.Error(w, err.Error(), 400)
 				return
 			}
+			a.record(r, audit.Entry{Action: "server.delete", ServerID: id, Details: map[string]any{"name": name, "force": skipBackup}})
 			w.WriteHeader(204)
 			return
 		default:
@@ -102,24 +150,66 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 		}
 		log.Info("API request to start server", "id", id)
 		if err := s.Start(a.bus); err != nil {
-			http.Error(w, err.Error(), 400); return
+			http.Error(w, err.Error(), errorStatus(err)); return
 		}
+		a.record(r, audit.Entry{Action: "server.start", ServerID: id})
 		writeJSON(w, s.Info())
 	case "stop":
 		if r.Method != http.MethodPost {
 			w.WriteHeader(405); return
 		}
-		log.Info("API request to stop server", "id", id)
-		s.Stop(a.bus)
+		// POST /servers/{id}/stop/cancel - abort a delayed stop or restart
+		if len(parts) == 3 && parts[2] == "cancel" {
+			log.Info("API request to cancel pending stop", "id", id)
+			if err := s.CancelStop(a.bus); err != nil {
+				http.Error(w, err.Error(), 409); return
+			}
+			a.record(r, audit.Entry{Action: "server.stop_cancel", ServerID: id})
+			writeJSON(w, s.Info())
+			return
+		}
+		opts, err := decodeStopOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), 400); return
+		}
+		log.Info("API request to stop server", "id", id, "delaySec", opts.DelaySec)
+		if opts.DelaySec > 0 {
+			if err := s.StopAfter(a.bus, opts); err != nil {
+				http.Error(w, err.Error(), 400); return
+			}
+		} else if err := s.Stop(a.bus); err != nil {
+			http.Error(w, err.Error(), errorStatus(err)); return
+		}
+		a.record(r, audit.Entry{Action: "server.stop", ServerID: id, Details: stopDetails(opts)})
+		writeJSON(w, s.Info())
+	case "kill":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(405); return
+		}
+		log.Warn("API request to kill server", "id", id)
+		if err := s.Kill(a.bus); err != nil {
+			http.Error(w, err.Error(), errorStatus(err)); return
+		}
+		a.record(r, audit.Entry{Action: "server.kill", ServerID: id})
 		writeJSON(w, s.Info())
 	case "restart":
 		if r.Method != http.MethodPost {
 			w.WriteHeader(405); return
 		}
-		log.Info("API request to restart server", "id", id)
-		if err := s.Restart(a.bus); err != nil {
+		opts, err := decodeStopOptions(r)
+		if err != nil {
 			http.Error(w, err.Error(), 400); return
 		}
+		log.Info("API request to restart server", "id", id, "delaySec", opts.DelaySec)
+		if opts.DelaySec > 0 {
+			err = s.RestartAfter(a.bus, opts)
+		} else {
+			err = s.Restart(a.bus)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err)); return
+		}
+		a.record(r, audit.Entry{Action: "server.restart", ServerID: id, Details: stopDetails(opts)})
 		writeJSON(w, s.Info())
 	case "cmd":
 		if r.Method != http.MethodPost {
@@ -133,7 +223,38 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 		if err := s.SendCommand(body.Command); err != nil {
 			http.Error(w, err.Error(), 400); return
 		}
+		a.record(r, audit.Entry{Action: "server.cmd", ServerID: id, Command: body.Command})
 		w.WriteHeader(204)
+	case "exec":
+		// POST /servers/{id}/exec - run a command over RCON and return its output
+		if r.Method != http.MethodPost {
+			w.WriteHeader(405); return
+		}
+		var body struct{ Command string `json:"command"` }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), 400); return
+		}
+		log.Info("API request to execute command", "id", id, "cmd", body.Command)
+		out, err := s.Exec(body.Command)
+		if err != nil {
+			status := 502
+			if errors.Is(err, manager.ErrRconDisabled) || errors.Is(err, rcon.ErrCommandTooLong) {
+				status = 400
+			}
+			http.Error(w, err.Error(), status); return
+		}
+		a.record(r, audit.Entry{Action: "server.exec", ServerID: id, Command: body.Command})
+		writeJSON(w, map[string]string{"output": out})
+	case "players":
+		// GET /servers/{id}/players - players currently online
+		if r.Method != http.MethodGet {
+			w.WriteHeader(405); return
+		}
+		writeJSON(w, s.OnlinePlayers())
+	case "sessions":
+		a.handleSessions(w, r, id)
+	case "stats":
+		a.handleStats(w, r, id)
 	case "logs":
 		if r.Method != http.MethodGet { w.WriteHeader(405); return }
 		log.Debug("fetching server logs", "id", id)
@@ -159,6 +280,10 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type","text/plain")
 		_, _ = w.Write([]byte(text))
 		return
+	case "backups":
+		a.handleBackups(w, r, id, parts)
+	case "schedules":
+		a.handleSchedules(w, r, id, parts)
 	case "properties":
 		if r.Method == http.MethodGet {
 			// GET /servers/{id}/properties - fetch server.properties
@@ -169,6 +294,9 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, err.Error(), 500)
 				return
 			}
+			if !identity(r).Can(id, auth.RoleAdmin) {
+				redactProperties(props)
+			}
 			writeJSON(w, props)
 			return
 		} else if r.Method == http.MethodPost {
@@ -198,6 +326,8 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			
+			changes := audit.Diff(props, stringUpdates)
+
 			// Update with new values
 			for key, value := range stringUpdates {
 				props[key] = value
@@ -209,6 +339,10 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, err.Error(), 500)
 				return
 			}
+			if len(changes) > 0 {
+				redactChanges(changes)
+				a.record(r, audit.Entry{Action: "server.properties", ServerID: id, Changes: changes})
+			}
 			
 			writeJSON(w, props)
 			return
@@ -216,11 +350,31 @@ func (a *API) handleServerByID(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(405)
 			return
 		}
+	case "whitelist", "ops", "banned-players", "banned-ips":
+		list, _ := manager.ParseAccessList(action)
+		a.handleAccess(w, r, s, id, list, parts)
 	default:
 		http.NotFound(w, r)
 	}
 }
 
+// decodeStopOptions reads the optional {"delaySec":..,"message":..} body of stop/restart
+func decodeStopOptions(r *http.Request) (manager.StopOptions, error) {
+	var opts manager.StopOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// stopDetails is the audit detail of a delayed stop or restart.
+func stopDetails(opts manager.StopOptions) map[string]any {
+	if opts.DelaySec <= 0 {
+		return nil
+	}
+	return map[string]any{"delaySec": opts.DelaySec, "message": opts.Message}
+}
+
 // kleines Tail (ohne extra util-Import)
 func tailLines(path string, n int) (string, error) {
 	b, err := os.ReadFile(path)
@@ -277,50 +431,43 @@ func handleVersions(w http.ResponseWriter, r *http.Request) {
 }
 
 
+// handleRuntimes lists the installed Java runtimes; ?refresh=1 rescans them.
+func (a *API) handleRuntimes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	if !allow(w, r, "", auth.RoleViewer) {
+		return
+	}
+	refresh := r.URL.Query().Get("refresh")
+	writeJSON(w, a.mgr.Runtimes(refresh == "1" || refresh == "true"))
+}
+
 func (a *API) handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	
+	// Player counts arrive as server.info events from each server's status poller
+	caller := identity(r)
 	sub := a.bus.Subscribe()
 	defer a.bus.Unsubscribe(sub)
 	
-	// Send periodic server info updates for real-time player counts
-	ticker := time.NewTicker(3 * time.Second)
-	defer ticker.Stop()
-	
 	for {
 		select {
 		case <-r.Context().Done():
 			return
 		case ev := <-sub.Ch:
+			if ev.ServerID != "" && !caller.Can(ev.ServerID, auth.RoleViewer) {
+				continue
+			}
 			b, _ := json.Marshal(ev)
 			w.Write([]byte("event: " + ev.Type + "\n"))
 			w.Write([]byte("data: " + string(b) + "\n\n"))
 			if f, ok := w.(http.Flusher); ok {
 				f.Flush()
 			}
-		case <-ticker.C:
-			// Publish server info updates for all running servers
-			for _, s := range a.mgr.List() {
-				if s.State == manager.StateRunning {
-					// Get fresh server info
-					if srv, ok := a.mgr.Get(s.Config.ID); ok {
-						ev := events.Event{
-							Type:     "server.info",
-							ServerID: s.Config.ID,
-							Data:     srv.Info(),
-						}
-						b, _ := json.Marshal(ev)
-						w.Write([]byte("event: server.info\n"))
-						w.Write([]byte("data: " + string(b) + "\n\n"))
-						if f, ok := w.(http.Flusher); ok {
-							f.Flush()
-						}
-					}
-				}
-			}
 		}
 	}
 }
@@ -330,11 +477,54 @@ func writeJSON(w http.ResponseWriter, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func withCORS(next http.Handler) http.Handler {
+// errorStatus maps lifecycle errors to 409 Conflict and everything else to 400.
+func errorStatus(err error) int {
+	var te *manager.TransitionError
+	if errors.As(err, &te) || errors.Is(err, manager.ErrStillRunning) || errors.Is(err, manager.ErrShuttingDown) {
+		return 409
+	}
+	return 400
+}
+
+// secretProperties give full console access to whoever knows them: RCON and
+// the management server listen on all interfaces unless server-ip is set.
+var secretProperties = []string{"rcon.password", "management-server-secret"}
+
+const redacted = "********"
+
+// redactProperties hides secretProperties from non-admins.
+func redactProperties(props map[string]string) {
+	for _, k := range secretProperties {
+		if v, ok := props[k]; ok && v != "" {
+			props[k] = redacted
+		}
+	}
+}
+
+// redactChanges keeps secrets out of the audit log.
+func redactChanges(changes []audit.Change) {
+	for i, c := range changes {
+		if !slices.Contains(secretProperties, c.Key) {
+			continue
+		}
+		changes[i].New = redacted
+		if c.Old != nil {
+			old := redacted
+			changes[i].Old = &old
+		}
+	}
+}
+
+// withCORS lets the listed origins call the API from a browser. Other origins
+// get no CORS headers, so browsers keep their pages from reading responses.
+func withCORS(origins []string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,DELETE,OPTIONS")
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(origins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
+		}
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(200)
 			return
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/audit"
+	"obsidian/internal/manager"
+)
+
+// handleSchedules serves /servers/{id}/schedules and /servers/{id}/schedules/{scheduleId}
+func (a *API) handleSchedules(w http.ResponseWriter, r *http.Request, id string, parts []string) {
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			list, err := a.mgr.ListSchedules(id)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			writeJSON(w, list)
+		case http.MethodPost:
+			sc := manager.Schedule{Enabled: true}
+			if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			log.Info("API request to create schedule", "id", id, "action", sc.Action, "cron", sc.Cron)
+			created, err := a.mgr.CreateSchedule(id, sc)
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			a.record(r, audit.Entry{Action: "schedule.create", ServerID: id, Details: scheduleDetails(created)})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			writeJSON(w, created)
+		default:
+			w.WriteHeader(405)
+		}
+		return
+	}
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	sid := parts[2]
+	var (
+		sc  manager.Schedule
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		sc, err = a.mgr.GetSchedule(id, sid)
+	case http.MethodPut:
+		upd := manager.Schedule{Enabled: true}
+		if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		log.Info("API request to update schedule", "id", id, "schedule", sid)
+		sc, err = a.mgr.UpdateSchedule(id, sid, upd)
+	case http.MethodDelete:
+		log.Info("API request to delete schedule", "id", id, "schedule", sid)
+		if err = a.mgr.DeleteSchedule(id, sid); err == nil {
+			a.record(r, audit.Entry{Action: "schedule.delete", ServerID: id, Details: map[string]any{"schedule": sid}})
+			w.WriteHeader(204)
+			return
+		}
+	default:
+		w.WriteHeader(405)
+		return
+	}
+	if err != nil {
+		if errors.Is(err, manager.ErrScheduleNotFound) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if r.Method == http.MethodPut {
+		a.record(r, audit.Entry{Action: "schedule.update", ServerID: id, Details: scheduleDetails(sc)})
+	}
+	writeJSON(w, sc)
+}
+
+func scheduleDetails(sc manager.Schedule) map[string]any {
+	d := map[string]any{"schedule": sc.ID, "cron": sc.Cron, "action": sc.Action, "enabled": sc.Enabled}
+	if sc.Command != "" {
+		d["command"] = sc.Command
+	}
+	return d
+}
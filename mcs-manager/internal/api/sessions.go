@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"obsidian/internal/manager"
+)
+
+// handleSessions serves GET /servers/{id}/sessions?player=&since=&until=&limit=,
+// newest first. Players still online are included with leftAt set to now.
+func (a *API) handleSessions(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	flt, err := sessionFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	list, err := a.mgr.Sessions(id, flt)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	writeJSON(w, list)
+}
+
+// handleStats serves GET /servers/{id}/stats?player=&since=&until= with
+// playtime per player, unique players and peak per day and the overall peak.
+// The window defaults to the last 7 days and spans at most 366.
+func (a *API) handleStats(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+	flt, err := sessionFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if !flt.Since.IsZero() && !flt.Until.IsZero() && !flt.Since.Before(flt.Until) {
+		http.Error(w, "since must be before until", 400)
+		return
+	}
+	stats, err := a.mgr.PlayerStats(id, flt)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		if errors.Is(err, manager.ErrStatsWindow) {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	writeJSON(w, stats)
+}
+
+// sessionFilter parses player, RFC 3339 since/until and limit.
+func sessionFilter(q url.Values) (manager.SessionFilter, error) {
+	flt := manager.SessionFilter{Player: q.Get("player")}
+	var err error
+	if v := q.Get("since"); v != "" {
+		if flt.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return flt, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if flt.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return flt, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if flt.Limit, err = strconv.Atoi(v); err != nil {
+			return flt, fmt.Errorf("invalid limit: %w", err)
+		}
+	}
+	return flt, nil
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/audit"
+	"obsidian/internal/auth"
+	"obsidian/internal/manager"
+)
+
+// handleWatches serves /watches and /watches/{id} for monitored external servers.
+func (a *API) handleWatches(w http.ResponseWriter, r *http.Request) {
+	need := auth.RoleAdmin
+	if r.Method == http.MethodGet {
+		need = auth.RoleViewer
+	}
+	if !allow(w, r, "", need) {
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/watches"), "/")
+
+	if id == "" {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, a.mgr.ListWatches())
+		case http.MethodPost:
+			var ws manager.WatchedServer
+			if err := json.NewDecoder(r.Body).Decode(&ws); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			log.Info("API request to watch server", "host", ws.Host, "port", ws.Port)
+			created, err := a.mgr.CreateWatch(ws)
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			a.record(r, audit.Entry{Action: "watch.create", Details: map[string]any{"watch": created.ID, "host": created.Host, "port": created.Port}})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(201)
+			writeJSON(w, created)
+		default:
+			w.WriteHeader(405)
+		}
+		return
+	}
+
+	var (
+		info manager.WatchInfo
+		err  error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		info, err = a.mgr.GetWatch(id)
+	case http.MethodPut:
+		var ws manager.WatchedServer
+		if err := json.NewDecoder(r.Body).Decode(&ws); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		if info, err = a.mgr.UpdateWatch(id, ws); err == nil {
+			a.record(r, audit.Entry{Action: "watch.update", Details: map[string]any{"watch": id, "host": info.Host, "port": info.Port}})
+		}
+	case http.MethodDelete:
+		if err = a.mgr.DeleteWatch(id); err == nil {
+			a.record(r, audit.Entry{Action: "watch.delete", Details: map[string]any{"watch": id}})
+			w.WriteHeader(204)
+			return
+		}
+	default:
+		w.WriteHeader(405)
+		return
+	}
+	if err != nil {
+		if errors.Is(err, manager.ErrWatchNotFound) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	writeJSON(w, info)
+}
@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const defaultLimit = 100
+
+// MaxLimit caps how many entries a single Query returns.
+const MaxLimit = 1000
+
+// Entry is one audited action. Changes is set for edits of key/value settings
+// such as server.properties.
+type Entry struct {
+	Time     time.Time      `json:"time"`
+	ActorID  string         `json:"actorId"`
+	Actor    string         `json:"actor"`
+	Action   string         `json:"action"`
+	ServerID string         `json:"serverId,omitempty"`
+	Command  string         `json:"command,omitempty"`
+	Changes  []Change       `json:"changes,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+	Remote   string         `json:"remote,omitempty"`
+}
+
+type Change struct {
+	Key string  `json:"key"`
+	Old *string `json:"old"` // nil when the key was added
+	New string  `json:"new"`
+}
+
+// Filter selects entries for Query. Zero values match everything.
+type Filter struct {
+	ServerID string
+	Actor    string // user id or name
+	Action   string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// Log appends entries as JSON lines to <root>/audit.jsonl.
+type Log struct {
+	mu   sync.Mutex
+	file string
+}
+
+func Open(root string) (*Log, error) {
+	l := &Log{file: filepath.Join(root, "audit.jsonl")}
+	f, err := os.OpenFile(l.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return l, f.Close()
+}
+
+func (l *Log) Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Error("failed to encode audit entry", "action", e.Action, "err", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Error("failed to open audit log", "err", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		log.Error("failed to write audit entry", "action", e.Action, "err", err)
+	}
+}
+
+// Query returns matching entries, newest first.
+func (l *Log) Query(flt Filter) ([]Entry, error) {
+	if flt.Limit <= 0 {
+		flt.Limit = defaultLimit
+	}
+	flt.Limit = min(flt.Limit, MaxLimit)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Entry{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	// The file is in chronological order; keep a ring of the last Limit matches.
+	ring := make([]Entry, 0, flt.Limit)
+	next := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		if !flt.match(e) {
+			continue
+		}
+		if len(ring) < flt.Limit {
+			ring = append(ring, e)
+		} else {
+			ring[next] = e
+		}
+		next = (next + 1) % flt.Limit
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]Entry, 0, len(ring))
+	for i := 0; i < len(ring); i++ {
+		out = append(out, ring[(next-1-i+len(ring))%len(ring)])
+	}
+	return out, nil
+}
+
+func (flt Filter) match(e Entry) bool {
+	if flt.ServerID != "" && e.ServerID != flt.ServerID {
+		return false
+	}
+	if flt.Actor != "" && e.ActorID != flt.Actor && e.Actor != flt.Actor {
+		return false
+	}
+	if flt.Action != "" && e.Action != flt.Action {
+		return false
+	}
+	if !flt.Since.IsZero() && e.Time.Before(flt.Since) {
+		return false
+	}
+	if !flt.Until.IsZero() && e.Time.After(flt.Until) {
+		return false
+	}
+	return true
+}
+
+// Diff lists the keys of upd whose value differs from old.
+func Diff(old, upd map[string]string) []Change {
+	var out []Change
+	for k, v := range upd {
+		prev, ok := old[k]
+		if ok && prev == v {
+			continue
+		}
+		c := Change{Key: k, New: v}
+		if ok {
+			c.Old = &prev
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
@@ -0,0 +1,373 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/util"
+)
+
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+const tokenPrefix = "obs_"
+
+var (
+	ErrUnauthorized  = errors.New("invalid or expired token")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrTokenNotFound = errors.New("token not found")
+	ErrLastAdmin     = errors.New("cannot remove the last admin")
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+func (r Role) Valid() bool { return r.rank() > 0 }
+
+// Allows reports whether r is at least need.
+func (r Role) Allows(need Role) bool { return r.rank() >= need.rank() }
+
+// User is an account. Role applies to every server, Servers grants additional
+// roles on individual servers (e.g. operator on one server for a moderator).
+type User struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Role      Role            `json:"role,omitempty"`
+	Servers   map[string]Role `json:"servers,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// Token is an API token of a user. A non-empty Role caps what the token may do,
+// so an admin can hand out read-only tokens.
+type Token struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"userId"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type storedToken struct {
+	Token
+	Hash string `json:"hash"`
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	User  User
+	Token Token
+}
+
+// RoleFor returns the caller's effective role on a server; serverID "" asks
+// for the global role.
+func (id *Identity) RoleFor(serverID string) Role {
+	role := id.User.Role
+	if g, ok := id.User.Servers[serverID]; ok && serverID != "" && g.rank() > role.rank() {
+		role = g
+	}
+	if id.Token.Role != "" && id.Token.Role.rank() < role.rank() {
+		role = id.Token.Role
+	}
+	return role
+}
+
+func (id *Identity) Can(serverID string, need Role) bool {
+	return id.RoleFor(serverID).Allows(need)
+}
+
+// Store keeps users and token hashes in <root>/users.json.
+type Store struct {
+	mu     sync.Mutex
+	file   string
+	users  []User
+	tokens []storedToken
+}
+
+type storeFile struct {
+	Users  []User        `json:"users"`
+	Tokens []storedToken `json:"tokens"`
+}
+
+func Open(root string) (*Store, error) {
+	s := &Store{file: filepath.Join(root, "users.json")}
+	b, err := os.ReadFile(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var f storeFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	s.users, s.tokens = f.Users, f.Tokens
+	log.Info("loaded users", "count", len(s.users), "tokens", len(s.tokens))
+	return s, nil
+}
+
+func (s *Store) persistLocked() error {
+	b, err := json.MarshalIndent(storeFile{Users: s.users, Tokens: s.tokens}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.file)
+}
+
+// Bootstrap creates an "admin" user with a token when no users exist yet and
+// returns the token; it returns "" if users are already configured.
+func (s *Store) Bootstrap() (string, error) {
+	s.mu.Lock()
+	empty := len(s.users) == 0
+	s.mu.Unlock()
+	if !empty {
+		return "", nil
+	}
+	u, err := s.CreateUser(User{Name: "admin", Role: RoleAdmin})
+	if err != nil {
+		return "", err
+	}
+	_, secret, err := s.CreateToken(u.ID, Token{Name: "initial"})
+	return secret, err
+}
+
+// Authenticate resolves a bearer token to its user.
+func (s *Store) Authenticate(secret string) (*Identity, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, ErrUnauthorized
+	}
+	hash := hashToken(secret)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		if t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt) {
+			return nil, ErrUnauthorized
+		}
+		if i := s.userIndex(t.UserID); i >= 0 {
+			return &Identity{User: s.users[i], Token: t.Token}, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+func (s *Store) ListUsers() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]User(nil), s.users...)
+}
+
+func (s *Store) GetUser(id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.userIndex(id); i >= 0 {
+		return s.users[i], nil
+	}
+	return User{}, ErrUserNotFound
+}
+
+func (s *Store) CreateUser(u User) (User, error) {
+	if err := validateUser(u); err != nil {
+		return User{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.users {
+		if strings.EqualFold(o.Name, u.Name) {
+			return User{}, errors.New("user name already taken")
+		}
+	}
+	u.ID = util.RandID()
+	u.CreatedAt = time.Now()
+	s.users = append(s.users, u)
+	if err := s.persistLocked(); err != nil {
+		s.users = s.users[:len(s.users)-1]
+		return User{}, err
+	}
+	log.Info("user created", "id", u.ID, "name", u.Name, "role", u.Role)
+	return u, nil
+}
+
+// UpdateUser replaces name, role and server grants of a user.
+func (s *Store) UpdateUser(id string, upd User) (User, error) {
+	if err := validateUser(upd); err != nil {
+		return User{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.userIndex(id)
+	if i < 0 {
+		return User{}, ErrUserNotFound
+	}
+	if s.users[i].Role == RoleAdmin && upd.Role != RoleAdmin && s.adminCount() == 1 {
+		return User{}, ErrLastAdmin
+	}
+	u := s.users[i]
+	u.Name, u.Role, u.Servers = upd.Name, upd.Role, upd.Servers
+	old := s.users[i]
+	s.users[i] = u
+	if err := s.persistLocked(); err != nil {
+		s.users[i] = old
+		return User{}, err
+	}
+	log.Info("user updated", "id", u.ID, "name", u.Name, "role", u.Role)
+	return u, nil
+}
+
+// DeleteUser removes a user together with all of its tokens.
+func (s *Store) DeleteUser(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.userIndex(id)
+	if i < 0 {
+		return ErrUserNotFound
+	}
+	if s.users[i].Role == RoleAdmin && s.adminCount() == 1 {
+		return ErrLastAdmin
+	}
+	users, tokens := s.users, s.tokens
+	s.users = append(append([]User(nil), users[:i]...), users[i+1:]...)
+	s.tokens = nil
+	for _, t := range tokens {
+		if t.UserID != id {
+			s.tokens = append(s.tokens, t)
+		}
+	}
+	if err := s.persistLocked(); err != nil {
+		s.users, s.tokens = users, tokens
+		return err
+	}
+	log.Info("user deleted", "id", id)
+	return nil
+}
+
+func (s *Store) ListTokens(userID string) []Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Token{}
+	for _, t := range s.tokens {
+		if t.UserID == userID {
+			out = append(out, t.Token)
+		}
+	}
+	return out
+}
+
+// CreateToken issues a new token for a user. The secret is returned only here;
+// the store keeps just its SHA-256 hash.
+func (s *Store) CreateToken(userID string, t Token) (Token, string, error) {
+	if t.Role != "" && !t.Role.Valid() {
+		return Token{}, "", errors.New("invalid role")
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Token{}, "", err
+	}
+	secret := tokenPrefix + hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userIndex(userID) < 0 {
+		return Token{}, "", ErrUserNotFound
+	}
+	t.ID = util.RandID()
+	t.UserID = userID
+	t.CreatedAt = time.Now()
+	s.tokens = append(s.tokens, storedToken{Token: t, Hash: hashToken(secret)})
+	if err := s.persistLocked(); err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return Token{}, "", err
+	}
+	log.Info("token created", "id", t.ID, "user", userID, "name", t.Name)
+	return t, secret, nil
+}
+
+func (s *Store) RevokeToken(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.tokens {
+		if t.ID != id || t.UserID != userID {
+			continue
+		}
+		tokens := s.tokens
+		s.tokens = append(append([]storedToken(nil), tokens[:i]...), tokens[i+1:]...)
+		if err := s.persistLocked(); err != nil {
+			s.tokens = tokens
+			return err
+		}
+		log.Info("token revoked", "id", id, "user", userID)
+		return nil
+	}
+	return ErrTokenNotFound
+}
+
+func (s *Store) userIndex(id string) int {
+	for i, u := range s.users {
+		if u.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) adminCount() int {
+	n := 0
+	for _, u := range s.users {
+		if u.Role == RoleAdmin {
+			n++
+		}
+	}
+	return n
+}
+
+func validateUser(u User) error {
+	if strings.TrimSpace(u.Name) == "" {
+		return errors.New("name required")
+	}
+	if u.Role != "" && !u.Role.Valid() {
+		return errors.New("invalid role")
+	}
+	for id, r := range u.Servers {
+		if !r.Valid() {
+			return errors.New("invalid role for server " + id)
+		}
+	}
+	return nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
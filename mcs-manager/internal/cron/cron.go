@@ -0,0 +1,209 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression (minute hour day-of-month month day-of-week).
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar/dowStar track unrestricted day fields so that the usual cron rule applies:
+	// when both day fields are restricted, a time matches if either one matches.
+	domStar, dowStar bool
+	// hourStar keeps wildcard-hour jobs running in both copies of a repeated
+	// hour when clocks fall back, fixed-hour jobs only run in the first one
+	hourStar bool
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a standard cron expression such as "0 4 * * *" or "*/15 * * * mon-fri".
+// The macros @yearly, @monthly, @weekly, @daily, @midnight and @hourly are supported.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d", len(fields))
+	}
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// 7 is an alias for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	s.dowStar = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+	s.hourStar = strings.HasPrefix(fields[1], "*")
+	return s, nil
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func parseRange(expr string, f field) (uint64, error) {
+	step := 1
+	if i := strings.Index(expr, "/"); i >= 0 {
+		n, err := strconv.Atoi(expr[i+1:])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("cron: invalid step in %q", expr)
+		}
+		step = n
+		expr = expr[:i]
+	}
+	lo, hi := f.min, f.max
+	switch {
+	case expr == "*" || expr == "?":
+	case strings.Contains(expr, "-"):
+		bounds := strings.SplitN(expr, "-", 2)
+		var err error
+		if lo, err = parseValue(bounds[0], f); err != nil {
+			return 0, err
+		}
+		if hi, err = parseValue(bounds[1], f); err != nil {
+			return 0, err
+		}
+	default:
+		v, err := parseValue(expr, f)
+		if err != nil {
+			return 0, err
+		}
+		lo = v
+		if step == 1 {
+			hi = v
+		}
+	}
+	if lo > hi {
+		return 0, fmt.Errorf("cron: invalid range %q", expr)
+	}
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cron: invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("cron: value %d out of range [%d-%d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first activation time strictly after t, in t's location.
+// It returns the zero time if the schedule can never fire (e.g. "0 0 31 2 *").
+// Jobs in an hour skipped by a DST change run right after the gap, and
+// fixed-hour jobs run only once in an hour that is repeated.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Five years is enough to hit every valid day/month combination, including Feb 29.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if t.Minute() == 0 && s.inGap(t) {
+			return t
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 || (!s.hourStar && t.Add(-time.Hour).Hour() == t.Hour()) {
+			// Step in elapsed time, the wall clock may skip or repeat an hour.
+			// Fixed-hour jobs skip the second copy of a repeated hour.
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// inGap reports whether t is the first instant after clocks sprang forward
+// past an hour the schedule would have fired in.
+func (s *Schedule) inGap(t time.Time) bool {
+	prev := t.Add(-time.Minute)
+	for h := prev.Hour() + 1; h < t.Hour(); h++ {
+		if s.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
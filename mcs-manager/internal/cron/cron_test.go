@@ -0,0 +1,199 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"1-2-3 * * * *",
+		"@weekdays",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected an error", expr)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	bits := func(vs ...int) uint64 {
+		var b uint64
+		for _, v := range vs {
+			b |= 1 << uint(v)
+		}
+		return b
+	}
+	tests := []struct {
+		expr                          string
+		minute, hour, dom, month, dow uint64
+	}{
+		{"*/15 * * * *", bits(0, 15, 30, 45), bits(seq(0, 23)...), bits(seq(1, 31)...), bits(seq(1, 12)...), bits(seq(0, 7)...)},
+		{"0 9-17/4 * * *", bits(0), bits(9, 13, 17), bits(seq(1, 31)...), bits(seq(1, 12)...), bits(seq(0, 7)...)},
+		{"5,10-12 0 1,15 jan-mar mon-fri", bits(5, 10, 11, 12), bits(0), bits(1, 15), bits(1, 2, 3), bits(1, 2, 3, 4, 5)},
+		// A single value with a step runs from the value to the end of the range
+		{"50/5 0 * * *", bits(50, 55), bits(0), bits(seq(1, 31)...), bits(seq(1, 12)...), bits(seq(0, 7)...)},
+		// 7 is Sunday too
+		{"0 0 * * 7", bits(0), bits(0), bits(seq(1, 31)...), bits(seq(1, 12)...), bits(0, 7)},
+		{"@hourly", bits(0), bits(seq(0, 23)...), bits(seq(1, 31)...), bits(seq(1, 12)...), bits(seq(0, 7)...)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if s.minute != tt.minute || s.hour != tt.hour || s.dom != tt.dom || s.month != tt.month || s.dow != tt.dow {
+			t.Errorf("Parse(%q) = %b %b %b %b %b", tt.expr, s.minute, s.hour, s.dom, s.month, s.dow)
+		}
+	}
+}
+
+func seq(lo, hi int) []int {
+	var out []int
+	for v := lo; v <= hi; v++ {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "steps",
+			expr: "*/20 * * * *",
+			from: time.Date(2024, 5, 1, 10, 5, 30, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC),
+				time.Date(2024, 5, 1, 10, 40, 0, 0, time.UTC),
+				time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "strictly after",
+			expr: "0 4 * * *",
+			from: time.Date(2024, 5, 1, 4, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2024, 5, 2, 4, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "weekday range",
+			expr: "30 6 * * mon-fri",
+			from: time.Date(2024, 5, 3, 7, 0, 0, 0, time.UTC), // Friday
+			want: []time.Time{
+				time.Date(2024, 5, 6, 6, 30, 0, 0, time.UTC),
+				time.Date(2024, 5, 7, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			// With both day fields restricted either one matches: the 1st and every Monday
+			name: "day of month or day of week",
+			expr: "0 0 1 * 1",
+			from: time.Date(2024, 4, 26, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC), // Monday
+				time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),  // Wednesday the 1st
+				time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),  // Monday
+			},
+		},
+		{
+			// A wildcard day field does not widen the other one
+			name: "day of month only",
+			expr: "0 0 1 * *",
+			from: time.Date(2024, 4, 26, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "leap day",
+			expr: "0 12 29 2 *",
+			from: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "never",
+			expr: "0 0 31 2 *",
+			from: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{{}},
+		},
+		{
+			// 02:00 jumps to 03:00 on 31 March, the 02:30 job runs right after the gap
+			name: "spring forward",
+			expr: "30 2 * * *",
+			from: time.Date(2024, 3, 30, 12, 0, 0, 0, berlin),
+			want: []time.Time{
+				time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC), // 03:00 CEST
+				time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+			},
+		},
+		{
+			name: "spring forward hourly",
+			expr: "0 * * * *",
+			from: time.Date(2024, 3, 31, 1, 30, 0, 0, berlin),
+			want: []time.Time{
+				time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC), // 03:00 CEST
+				time.Date(2024, 3, 31, 2, 0, 0, 0, time.UTC), // 04:00 CEST
+			},
+		},
+		{
+			// 03:00 goes back to 02:00 on 27 October, a fixed-time job runs once
+			name: "fall back",
+			expr: "30 2 * * *",
+			from: time.Date(2024, 10, 26, 22, 0, 0, 0, time.UTC).In(berlin), // 00:00 CEST
+			want: []time.Time{
+				time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+				time.Date(2024, 10, 28, 1, 30, 0, 0, time.UTC), // 02:30 CET the next day
+			},
+		},
+		{
+			// Hourly jobs still run once per elapsed hour
+			name: "fall back hourly",
+			expr: "0 * * * *",
+			from: time.Date(2024, 10, 26, 23, 30, 0, 0, time.UTC).In(berlin), // 01:30 CEST
+			want: []time.Time{
+				time.Date(2024, 10, 27, 0, 0, 0, 0, time.UTC), // 02:00 CEST
+				time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC), // 02:00 CET
+				time.Date(2024, 10, 27, 2, 0, 0, 0, time.UTC), // 03:00 CET
+			},
+		},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		from := tt.from
+		for i, want := range tt.want {
+			got := s.Next(from)
+			if !got.Equal(want) {
+				t.Errorf("%s: Next #%d after %s = %s, want %s", tt.name, i+1, from, got, want.In(from.Location()))
+				break
+			}
+			if !got.IsZero() && got.Location() != from.Location() {
+				t.Errorf("%s: Next returned %s, want location %s", tt.name, got.Location(), from.Location())
+			}
+			from = got
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/util"
+)
+
+// AccessList is one of the player list files in a server directory.
+type AccessList string
+
+const (
+	ListWhitelist     AccessList = "whitelist"
+	ListOps           AccessList = "ops"
+	ListBannedPlayers AccessList = "banned-players"
+	ListBannedIPs     AccessList = "banned-ips"
+)
+
+var accessLists = []AccessList{ListWhitelist, ListOps, ListBannedPlayers, ListBannedIPs}
+
+func ParseAccessList(name string) (AccessList, bool) {
+	for _, l := range accessLists {
+		if string(l) == name {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// AccessEntry is an entry of whitelist.json, ops.json, banned-players.json or
+// banned-ips.json. Only the fields of the respective file are set.
+type AccessEntry struct {
+	UUID                string `json:"uuid,omitempty"`
+	Name                string `json:"name,omitempty"`
+	IP                  string `json:"ip,omitempty"`
+	Level               int    `json:"level,omitempty"`
+	BypassesPlayerLimit *bool  `json:"bypassesPlayerLimit,omitempty"`
+	Created             string `json:"created,omitempty"`
+	Source              string `json:"source,omitempty"`
+	Expires             string `json:"expires,omitempty"`
+	Reason              string `json:"reason,omitempty"`
+}
+
+// AccessRequest adds a player (by name) or, for banned-ips, an address.
+type AccessRequest struct {
+	Name   string `json:"name"`
+	IP     string `json:"ip"`
+	Level  int    `json:"level"`  // ops only, 1-4; defaults to op-permission-level
+	Reason string `json:"reason"` // bans only
+	// Expires is an RFC 3339 time for temporary bans, empty for permanent ones
+	Expires string `json:"expires"`
+}
+
+// Minecraft's date format in ban lists and usercache.json
+const mcTimeLayout = "2006-01-02 15:04:05 -0700"
+
+var (
+	ErrAccessEntryNotFound = errors.New("entry not found")
+	ErrServerStopping      = errors.New("server is stopping, try again once it has stopped")
+
+	playerNameRe = regexp.MustCompile(`^[\w.]{1,16}$`)
+)
+
+func (l AccessList) path(s *Server) string {
+	return filepath.Join(s.cfg.Path, string(l)+".json")
+}
+
+// AccessEntries reads a list file; a missing file is an empty list.
+func (s *Server) AccessEntries(l AccessList) ([]AccessEntry, error) {
+	out := []AccessEntry{}
+	b, err := os.ReadFile(l.path(s))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return out, nil
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// live reports whether list changes must go through the console. A running
+// server keeps the lists in memory and overwrites the files on its next change.
+func (s *Server) live() (bool, error) {
+	switch s.State() {
+	case StateStarting, StateRunning:
+		return true, nil
+	case StateStopping:
+		return false, ErrServerStopping
+	}
+	return false, nil
+}
+
+// AddAccess adds a player or address to a list. A running server gets the
+// matching console command (whitelist add, op, ban, ban-ip) so the change takes
+// effect immediately; otherwise the file is edited directly.
+func (s *Server) AddAccess(l AccessList, req AccessRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Reason = strings.Join(strings.Fields(req.Reason), " ")
+	if l == ListBannedIPs {
+		if net.ParseIP(req.IP) == nil {
+			return errors.New("invalid ip")
+		}
+	} else if !playerNameRe.MatchString(req.Name) {
+		return errors.New("invalid player name")
+	}
+	if l == ListOps && (req.Level < 0 || req.Level > 4) {
+		return errors.New("op level must be between 1 and 4")
+	}
+	var expires time.Time
+	if req.Expires != "" {
+		if l != ListBannedPlayers && l != ListBannedIPs {
+			return errors.New("expires only applies to bans")
+		}
+		var err error
+		if expires, err = time.Parse(time.RFC3339, req.Expires); err != nil {
+			return errors.New("invalid expires: " + err.Error())
+		}
+	}
+
+	live, err := s.live()
+	if err != nil {
+		return err
+	}
+	if live {
+		// The console commands have no way to set these
+		if l == ListOps && req.Level != 0 && req.Level != s.defaultOpLevel() {
+			return errors.New("op level can only be changed while the server is stopped")
+		}
+		if !expires.IsZero() {
+			return errors.New("temporary bans can only be added while the server is stopped")
+		}
+		var cmd string
+		switch l {
+		case ListWhitelist:
+			cmd = "whitelist add " + req.Name
+		case ListOps:
+			cmd = "op " + req.Name
+		case ListBannedPlayers:
+			cmd = strings.TrimSpace("ban " + req.Name + " " + req.Reason)
+		case ListBannedIPs:
+			cmd = strings.TrimSpace("ban-ip " + req.IP + " " + req.Reason)
+		}
+		return s.SendCommand(cmd)
+	}
+
+	e := AccessEntry{IP: req.IP}
+	if l != ListBannedIPs {
+		if e.UUID, e.Name, err = s.lookupProfile(req.Name); err != nil {
+			return err
+		}
+	}
+	switch l {
+	case ListOps:
+		e.Level = req.Level
+		if e.Level == 0 {
+			e.Level = s.defaultOpLevel()
+		}
+		bypass := false
+		e.BypassesPlayerLimit = &bypass
+	case ListBannedPlayers, ListBannedIPs:
+		e.Created = time.Now().Format(mcTimeLayout)
+		e.Source = "Server"
+		e.Expires = "forever"
+		if !expires.IsZero() {
+			e.Expires = expires.Format(mcTimeLayout)
+		}
+		e.Reason = req.Reason
+		if e.Reason == "" {
+			e.Reason = "Banned by an operator."
+		}
+	}
+
+	s.listMu.Lock()
+	defer s.listMu.Unlock()
+	list, err := s.AccessEntries(l)
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range list {
+		if list[i].matches(e.UUID, e.IP) {
+			list[i], replaced = e, true
+		}
+	}
+	if !replaced {
+		list = append(list, e)
+	}
+	log.Info("updated access list", "id", s.cfg.ID, "list", l, "name", e.Name, "ip", e.IP)
+	return writeAccessList(l.path(s), list)
+}
+
+// RemoveAccess removes an entry by player name, UUID or IP address.
+func (s *Server) RemoveAccess(l AccessList, key string) error {
+	live, err := s.live()
+	if err != nil {
+		return err
+	}
+	s.listMu.Lock()
+	defer s.listMu.Unlock()
+	list, err := s.AccessEntries(l)
+	if err != nil {
+		return err
+	}
+	idx := -1
+	for i := range list {
+		if list[i].matches(key, key) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return ErrAccessEntryNotFound
+	}
+	e := list[idx]
+
+	if live {
+		switch l {
+		case ListWhitelist:
+			return s.SendCommand("whitelist remove " + e.Name)
+		case ListOps:
+			return s.SendCommand("deop " + e.Name)
+		case ListBannedPlayers:
+			return s.SendCommand("pardon " + e.Name)
+		default:
+			return s.SendCommand("pardon-ip " + e.IP)
+		}
+	}
+	list = append(list[:idx], list[idx+1:]...)
+	log.Info("updated access list", "id", s.cfg.ID, "list", l, "removed", key)
+	return writeAccessList(l.path(s), list)
+}
+
+func (e *AccessEntry) matches(key, ip string) bool {
+	if ip != "" && e.IP == ip {
+		return true
+	}
+	return key != "" && (strings.EqualFold(e.UUID, key) || strings.EqualFold(e.Name, key))
+}
+
+// defaultOpLevel is the level the op command grants, op-permission-level.
+func (s *Server) defaultOpLevel() int {
+	props, err := util.ParseProperties(filepath.Join(s.cfg.Path, "server.properties"))
+	if err == nil {
+		if lvl, err := strconv.Atoi(props["op-permission-level"]); err == nil && lvl >= 1 && lvl <= 4 {
+			return lvl
+		}
+	}
+	return 4
+}
+
+// writeAccessList writes a list the way Minecraft does, two-space indented.
+func writeAccessList(file string, list []AccessEntry) error {
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
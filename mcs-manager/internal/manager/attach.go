@@ -0,0 +1,121 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/util"
+	"obsidian/pkg/events"
+)
+
+// runtimeState is written to <root>/run/<id>/runtime.json while a server process
+// is alive, so a restarted manager can find and re-adopt it.
+// startTimeSlack is how long after its process was created a server's
+// StartedAt may be recorded.
+const startTimeSlack = 10 * time.Second
+
+type runtimeState struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+	Jar       string    `json:"jar"`
+	Args      []string  `json:"args,omitempty"`
+}
+
+func (s *Server) runtimePath() string { return filepath.Join(s.runDir, "runtime.json") }
+func (s *Server) consolePath() string { return filepath.Join(s.runDir, "console.in") }
+func (s *Server) logPath() string     { return filepath.Join(s.cfg.Path, "mcs.log") }
+func (s *Server) jarPath() string     { return filepath.Join(s.cfg.Path, "server.jar") }
+
+func (s *Server) saveRuntime(rt runtimeState) {
+	if err := os.MkdirAll(s.runDir, 0o755); err != nil {
+		log.Warn("failed to create runtime directory", "id", s.cfg.ID, "err", err)
+		return
+	}
+	b, _ := json.Marshal(rt)
+	if err := os.WriteFile(s.runtimePath(), b, 0o644); err != nil {
+		log.Warn("failed to persist runtime state", "id", s.cfg.ID, "err", err)
+	}
+}
+
+func (s *Server) clearRuntime() {
+	_ = os.Remove(s.runtimePath())
+	_ = os.Remove(s.consolePath())
+}
+
+// reattach re-adopts a server process that survived a manager restart. It
+// resumes log streaming from the end of mcs.log and reconnects the console pipe.
+func (s *Server) reattach(bus *events.Bus) bool {
+	b, err := os.ReadFile(s.runtimePath())
+	if err != nil {
+		return false
+	}
+	var rt runtimeState
+	if err := json.Unmarshal(b, &rt); err != nil || rt.PID <= 0 {
+		s.clearRuntime()
+		return false
+	}
+	if !processAlive(rt.PID) || !processMatches(rt.PID, rt.Jar, rt.StartedAt) {
+		log.Info("server process from previous run is gone", "id", s.cfg.ID, "pid", rt.PID)
+		s.clearRuntime()
+		return false
+	}
+
+	stdin, err := reopenConsoleFIFO(s.consolePath())
+	if err != nil {
+		log.Warn("re-adopted server without console input", "id", s.cfg.ID, "err", err)
+	}
+	offset := int64(0)
+	if fi, err := os.Stat(s.logPath()); err == nil {
+		offset = fi.Size()
+	}
+	done := make(chan struct{})
+	s.mu.Lock()
+	if stdin != nil {
+		s.stdin = stdin
+	}
+	s.pid, s.startAt, s.done, s.cmdline = rt.PID, rt.StartedAt, done, rt.Args
+	s.limits = processLimits(rt.PID)
+	// The process is already up, so this is not a transition we drive ourselves
+	s.state = StateRunning
+	s.mu.Unlock()
+	log.Info("re-adopted running server", "id", s.cfg.ID, "name", s.cfg.Name, "pid", rt.PID)
+	bus.Publish(events.Event{Type: "server.adopted", ServerID: s.cfg.ID, Data: map[string]any{"pid": rt.PID}})
+
+	go s.follow(bus, offset, done)
+	go s.watchAdopted(bus, rt.PID, done)
+	go s.pollStatus(bus, done)
+	return true
+}
+
+// watchAdopted polls a process that is not our child, since it cannot be waited on.
+func (s *Server) watchAdopted(bus *events.Bus, pid int, done chan struct{}) {
+	for processAlive(pid) {
+		time.Sleep(time.Second)
+	}
+	var err error
+	if s.State() != StateStopping {
+		err = errors.New("process exited (exit status unknown)")
+	}
+	s.exited(bus, err, done)
+}
+
+// follow streams new lines of mcs.log, which the server process writes to directly.
+func (s *Server) follow(bus *events.Bus, offset int64, done <-chan struct{}) {
+	f, err := os.Open(s.logPath())
+	if err != nil {
+		log.Error("failed to open server log", "id", s.cfg.ID, "err", err)
+		return
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		log.Warn("failed to seek server log", "id", s.cfg.ID, "err", err)
+	}
+	r := util.NewFollowReader(f, done)
+	defer r.Close()
+	s.pipe(bus, r, "stdout")
+}
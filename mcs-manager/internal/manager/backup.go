@@ -0,0 +1,313 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/util"
+	"obsidian/pkg/events"
+)
+
+const (
+	backupExt = ".tar.gz"
+
+	// saveFlushTimeout bounds how long a backup waits for "save-all flush" to finish
+	saveFlushTimeout = 2 * time.Minute
+)
+
+type Backup struct {
+	ID        string    `json:"id"`
+	ServerID  string    `json:"serverId"`
+	CreatedAt time.Time `json:"createdAt"`
+	SizeBytes int64     `json:"sizeBytes"`
+}
+
+var ErrBackupNotFound = errors.New("backup not found")
+
+func (m *Manager) backupDir(serverID string) string {
+	return filepath.Join(m.root, "backups", serverID)
+}
+
+// CreateBackup archives the complete server directory (worlds, configs, plugins)
+// into <root>/backups/<id>/<backupId>.tar.gz.
+func (m *Manager) CreateBackup(id string) (Backup, error) {
+	s, ok := m.Get(id)
+	if !ok {
+		return Backup{}, os.ErrNotExist
+	}
+	b, err := m.archive(s)
+	if err != nil {
+		return Backup{}, err
+	}
+	m.bus.Publish(events.Event{Type: "backup.created", ServerID: id, Data: b})
+	return b, nil
+}
+
+func (m *Manager) archive(s *Server) (Backup, error) {
+	now := time.Now().UTC()
+	b := Backup{
+		ID:        now.Format("20060102-150405") + "-" + util.RandID()[:6],
+		ServerID:  s.cfg.ID,
+		CreatedAt: now,
+	}
+	log.Info("creating backup", "id", s.cfg.ID, "backup", b.ID, "path", s.cfg.Path)
+	dest := filepath.Join(m.backupDir(s.cfg.ID), b.ID+backupExt)
+	var size int64
+	err := s.withSavesPaused(func() error {
+		var err error
+		size, err = util.TarGzDir(s.cfg.Path, dest)
+		return err
+	})
+	if err != nil {
+		log.Error("failed to create backup", "id", s.cfg.ID, "backup", b.ID, "err", err)
+		return Backup{}, err
+	}
+	b.SizeBytes = size
+	log.Info("backup created", "id", s.cfg.ID, "backup", b.ID, "size", size)
+	return b, nil
+}
+
+// ListBackups returns all backups of a server, newest first.
+func (m *Manager) ListBackups(id string) ([]Backup, error) {
+	if _, ok := m.Get(id); !ok {
+		return nil, os.ErrNotExist
+	}
+	return m.readBackups(id)
+}
+
+func (m *Manager) readBackups(id string) ([]Backup, error) {
+	entries, err := os.ReadDir(m.backupDir(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Backup{}, nil
+		}
+		return nil, err
+	}
+	out := make([]Backup, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, backupExt) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		bid := strings.TrimSuffix(name, backupExt)
+		created := fi.ModTime().UTC()
+		if len(bid) >= 15 {
+			if t, err := time.Parse("20060102-150405", bid[:15]); err == nil {
+				created = t
+			}
+		}
+		out = append(out, Backup{ID: bid, ServerID: id, CreatedAt: created, SizeBytes: fi.Size()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, nil
+}
+
+func (m *Manager) backupPath(id, backupID string) (string, error) {
+	if backupID == "" || strings.ContainsAny(backupID, `/\`) || strings.Contains(backupID, "..") {
+		return "", ErrBackupNotFound
+	}
+	archive := filepath.Join(m.backupDir(id), backupID+backupExt)
+	if _, err := os.Stat(archive); err != nil {
+		return "", ErrBackupNotFound
+	}
+	return archive, nil
+}
+
+// RestoreBackup replaces the server directory with the contents of a backup.
+// The archive is unpacked next to the server directory first, so a failed
+// extraction leaves the current files untouched.
+func (m *Manager) RestoreBackup(id, backupID string) error {
+	s, ok := m.Get(id)
+	if !ok {
+		return os.ErrNotExist
+	}
+	if s.State().isActive() {
+		log.Warn("cannot restore backup while server is running", "id", id, "backup", backupID)
+		return errors.New("server running")
+	}
+	archive, err := m.backupPath(id, backupID)
+	if err != nil {
+		return err
+	}
+	log.Info("restoring backup", "id", id, "backup", backupID, "path", s.cfg.Path)
+
+	staging := s.cfg.Path + ".restore-" + util.RandID()
+	if err := util.ExtractTarGz(archive, staging); err != nil {
+		_ = os.RemoveAll(staging)
+		log.Error("failed to extract backup", "id", id, "backup", backupID, "err", err)
+		return err
+	}
+	old := s.cfg.Path + ".old-" + util.RandID()
+	hadOld := true
+	if err := os.Rename(s.cfg.Path, old); err != nil {
+		if !os.IsNotExist(err) {
+			_ = os.RemoveAll(staging)
+			return err
+		}
+		hadOld = false
+	}
+	if err := os.Rename(staging, s.cfg.Path); err != nil {
+		if hadOld {
+			_ = os.Rename(old, s.cfg.Path)
+		}
+		_ = os.RemoveAll(staging)
+		log.Error("failed to swap in restored directory", "id", id, "err", err)
+		return err
+	}
+	if hadOld {
+		_ = os.RemoveAll(old)
+	}
+	m.bus.Publish(events.Event{Type: "backup.restored", ServerID: id, Data: map[string]any{"backupId": backupID}})
+	log.Info("backup restored", "id", id, "backup", backupID)
+	return nil
+}
+
+// deletedConfigFile keeps the configuration of a deleted server next to its
+// backups, so RestoreDeleted can bring it back.
+const deletedConfigFile = "server.json"
+
+// DeletedServer is a server that was deleted but still has backups.
+type DeletedServer struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Backups []Backup `json:"backups"`
+}
+
+func (m *Manager) saveDeletedConfig(cfg ServerConfig) error {
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.backupDir(cfg.ID), deletedConfigFile), b, 0o600)
+}
+
+func (m *Manager) loadDeletedConfig(id string) (ServerConfig, error) {
+	var cfg ServerConfig
+	b, err := os.ReadFile(filepath.Join(m.backupDir(id), deletedConfigFile))
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(b, &cfg)
+	return cfg, err
+}
+
+// ListDeleted returns the backups left behind by deleted servers.
+func (m *Manager) ListDeleted() ([]DeletedServer, error) {
+	entries, err := os.ReadDir(filepath.Join(m.root, "backups"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []DeletedServer{}, nil
+		}
+		return nil, err
+	}
+	out := []DeletedServer{}
+	for _, e := range entries {
+		id := e.Name()
+		if !e.IsDir() {
+			continue
+		}
+		if _, ok := m.Get(id); ok {
+			continue
+		}
+		list, err := m.readBackups(id)
+		if err != nil || len(list) == 0 {
+			continue
+		}
+		d := DeletedServer{ID: id, Name: id, Backups: list}
+		if cfg, err := m.loadDeletedConfig(id); err == nil && cfg.Name != "" {
+			d.Name = cfg.Name
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// RestoreDeleted recreates a deleted server from one of its backups, with the
+// configuration it had when it was deleted.
+func (m *Manager) RestoreDeleted(id, backupID string) (*Server, error) {
+	if _, ok := m.Get(id); ok {
+		return nil, fmt.Errorf("server %s exists, restore the backup into it instead", id)
+	}
+	archive, err := m.backupPath(id, backupID)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := m.loadDeletedConfig(id)
+	if err != nil {
+		log.Error("no configuration saved for deleted server", "id", id, "err", err)
+		return nil, fmt.Errorf("no configuration saved for deleted server %s", id)
+	}
+	if _, err := os.Stat(cfg.Path); err == nil {
+		return nil, fmt.Errorf("server directory %s already exists", cfg.Path)
+	}
+	log.Info("restoring deleted server", "id", id, "name", cfg.Name, "backup", backupID, "path", cfg.Path)
+
+	staging := cfg.Path + ".restore-" + util.RandID()
+	if err := util.ExtractTarGz(archive, staging); err != nil {
+		_ = os.RemoveAll(staging)
+		log.Error("failed to extract backup", "id", id, "backup", backupID, "err", err)
+		return nil, err
+	}
+	if err := os.Rename(staging, cfg.Path); err != nil {
+		_ = os.RemoveAll(staging)
+		log.Error("failed to move restored directory into place", "id", id, "err", err)
+		return nil, err
+	}
+
+	s := m.newServer(cfg)
+	m.mu.Lock()
+	m.items[id] = s
+	m.mu.Unlock()
+	_ = m.persist()
+	m.bus.Publish(events.Event{Type: "server.created", ServerID: id, Data: cfg})
+	m.bus.Publish(events.Event{Type: "backup.restored", ServerID: id, Data: map[string]any{"backupId": backupID}})
+	log.Info("deleted server restored", "id", id, "backup", backupID)
+	return s, nil
+}
+
+// withSavesPaused runs fn while a running server has autosaving disabled and all
+// chunks flushed to disk, so region files are not modified mid-copy. save-on is
+// always re-issued once autosave was turned off, even if fn fails.
+func (s *Server) withSavesPaused(fn func() error) error {
+	switch s.State() {
+	case StateStarting:
+		return errors.New("server is starting, try again once it is ready")
+	case StateRunning:
+	default:
+		return fn()
+	}
+	if err := s.SendCommand("save-off"); err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.SendCommand("save-on"); err != nil {
+			log.Error("failed to re-enable saving", "id", s.cfg.ID, "err", err)
+		}
+	}()
+	log.Debug("flushing world before backup", "id", s.cfg.ID)
+	if _, err := s.SendCommandAndWait("save-all flush", isSaveComplete, saveFlushTimeout); err != nil {
+		log.Error("world flush did not complete", "id", s.cfg.ID, "err", err)
+		return err
+	}
+	return fn()
+}
+
+// saveDoneRe matches the server's reply to save-all, not a player saying it.
+var saveDoneRe = regexp.MustCompile(infoPrefix + `Saved the game$`)
+
+func isSaveComplete(line string) bool {
+	return saveDoneRe.MatchString(line)
+}
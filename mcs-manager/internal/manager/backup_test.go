@@ -0,0 +1,16 @@
+package manager
+
+import "testing"
+
+func TestIsSaveComplete(t *testing.T) {
+	for line, want := range map[string]bool{
+		"[12:00:00] [Server thread/INFO]: Saved the game":            true,
+		"[12:00:00 INFO]: Saved the game":                            true,
+		"[12:00:00] [Server thread/INFO]: <Steve> Saved the game":    false,
+		"[12:00:00] [Server thread/INFO]: <Steve> ]: Saved the game": false,
+	} {
+		if got := isSaveComplete(line); got != want {
+			t.Errorf("isSaveComplete(%q) = %v", line, got)
+		}
+	}
+}
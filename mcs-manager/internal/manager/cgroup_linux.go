@@ -0,0 +1,266 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupHost is the manager's own cgroup, which gets one child per server.
+var cgroupHost struct {
+	once sync.Once
+	base string
+	err  error
+}
+
+func cgroupBase() (string, error) {
+	cgroupHost.once.Do(func() {
+		cgroupHost.base, cgroupHost.err = setupCgroupHost()
+		if cgroupHost.err != nil {
+			log.Warn("cgroups unavailable, resource limits are not applied", "err", cgroupHost.err)
+		} else {
+			log.Info("using cgroup for server resource limits", "path", cgroupHost.base)
+		}
+	})
+	return cgroupHost.base, cgroupHost.err
+}
+
+// setupCgroupHost prepares the manager's cgroup v2 for per-server children.
+// Controllers can only be enabled for children of a cgroup without processes
+// of its own, so the manager first moves itself into a "manager" leaf. That is
+// only done in a cgroup delegated to the manager, e.g. by systemd's
+// Delegate=yes or a container, never in one shared with a login session.
+func setupCgroupHost() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", errors.New("no cgroup v2 hierarchy at " + cgroupRoot)
+	}
+	// Processes are started right inside their cgroup with CLONE_INTO_CGROUP
+	if !kernelAtLeast(5, 7) {
+		return "", errors.New("starting processes in a cgroup needs linux 5.7 or newer")
+	}
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	rel := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if p, ok := strings.CutPrefix(line, "0::"); ok {
+			rel = p
+		}
+	}
+	if rel == "" {
+		return "", errors.New("manager is not in a cgroup v2")
+	}
+	base := filepath.Join(cgroupRoot, rel)
+
+	fi, err := os.Stat(base)
+	if err != nil {
+		return "", err
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Geteuid() {
+		return "", fmt.Errorf("cgroup %s is not delegated to the manager", rel)
+	}
+	procs, err := os.ReadFile(filepath.Join(base, "cgroup.procs"))
+	if err != nil {
+		return "", err
+	}
+	pids := strings.Fields(string(procs))
+	for _, pid := range pids {
+		if !ownProcess(pid) {
+			return "", fmt.Errorf("cgroup %s is shared with other processes, delegate one to the manager", rel)
+		}
+	}
+
+	leaf := filepath.Join(base, "manager")
+	if err := os.Mkdir(leaf, 0o755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	for _, pid := range pids {
+		if err := writeCgroupFile(leaf, "cgroup.procs", pid); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("moving process %s out of %s: %w", pid, base, err)
+		}
+	}
+
+	avail, err := os.ReadFile(filepath.Join(base, "cgroup.controllers"))
+	if err != nil {
+		return "", err
+	}
+	for _, c := range []string{"cpu", "memory", "io"} {
+		if !slices.Contains(strings.Fields(string(avail)), c) {
+			log.Warn("cgroup controller not available", "controller", c)
+			continue
+		}
+		if err := writeCgroupFile(base, "cgroup.subtree_control", "+"+c); err != nil {
+			log.Warn("failed to enable cgroup controller", "controller", c, "err", err)
+		}
+	}
+	return base, nil
+}
+
+// ownProcess reports whether pid is the manager or one of its children, e.g.
+// a running hook.
+func ownProcess(pid string) bool {
+	self := strconv.Itoa(os.Getpid())
+	if pid == self {
+		return true
+	}
+	b, err := os.ReadFile(filepath.Join("/proc", pid, "stat"))
+	if err != nil {
+		// Already gone
+		return true
+	}
+	// "pid (comm) state ppid ...", comm may contain spaces and parentheses
+	i := strings.LastIndexByte(string(b), ')')
+	f := strings.Fields(string(b[i+1:]))
+	return len(f) > 1 && f[1] == self
+}
+
+func kernelAtLeast(major, minor int) bool {
+	var u syscall.Utsname
+	if err := syscall.Uname(&u); err != nil {
+		return false
+	}
+	var release []byte
+	for _, c := range u.Release {
+		if c == 0 {
+			break
+		}
+		release = append(release, byte(c))
+	}
+	var maj, min int
+	if _, err := fmt.Sscanf(string(release), "%d.%d", &maj, &min); err != nil {
+		return false
+	}
+	return maj > major || (maj == major && min >= minor)
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644)
+}
+
+func (s *Server) cgroupDir(base string) string {
+	return filepath.Join(base, "server-"+s.cfg.ID)
+}
+
+// prepareLimits creates the server's cgroup with the configured limits and
+// opens it for startInCgroup. Failures are reported, not fatal: the server
+// then runs without limits.
+func (s *Server) prepareLimits() (*CgroupLimits, *os.File) {
+	if !hasLimits(s.cfg) {
+		return nil, nil
+	}
+	base, err := cgroupBase()
+	if err != nil {
+		return &CgroupLimits{Error: err.Error()}, nil
+	}
+	dir := s.cgroupDir(base)
+	if err := os.Mkdir(dir, 0o755); err != nil && !os.IsExist(err) {
+		log.Warn("failed to create server cgroup", "id", s.cfg.ID, "err", err)
+		return &CgroupLimits{Error: err.Error()}, nil
+	}
+
+	cpu, mem, io := "max 100000", "max", "default 100"
+	if s.cfg.CPUMax > 0 {
+		cpu = strconv.Itoa(int(s.cfg.CPUMax*100000)) + " 100000"
+	}
+	if s.cfg.MemoryMaxMB > 0 {
+		mem = strconv.FormatInt(int64(s.cfg.MemoryMaxMB)<<20, 10)
+	}
+	if s.cfg.IOWeight > 0 {
+		io = "default " + strconv.Itoa(s.cfg.IOWeight)
+	}
+	var errs []string
+	for _, f := range []struct{ name, value string }{{"cpu.max", cpu}, {"memory.max", mem}, {"io.weight", io}} {
+		if err := writeCgroupFile(dir, f.name, f.value); err != nil {
+			errs = append(errs, f.name+": "+err.Error())
+		}
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		log.Warn("failed to open server cgroup", "id", s.cfg.ID, "err", err)
+		_ = os.Remove(dir)
+		return &CgroupLimits{Error: err.Error()}, nil
+	}
+	out := readCgroupLimits(dir)
+	if len(errs) > 0 {
+		out.Error = strings.Join(errs, "; ")
+		log.Warn("some resource limits were not applied", "id", s.cfg.ID, "err", out.Error)
+	}
+	log.Info("applying resource limits", "id", s.cfg.ID, "cgroup", out.Cgroup, "cpuMax", out.CPUMax, "memoryMaxMb", out.MemoryMaxMB, "ioWeight", out.IOWeight)
+	return out, f
+}
+
+// startInCgroup makes cmd start inside the cgroup, so the JVM never runs
+// without its limits and everything it spawns stays inside.
+func startInCgroup(cmd *exec.Cmd, cgroup *os.File) {
+	if cgroup == nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+}
+
+// processLimits reads the limits of a re-adopted server from the cgroup its
+// process lives in.
+func processLimits(pid int) *CgroupLimits {
+	b, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if rel, ok := strings.CutPrefix(line, "0::"); ok && strings.HasPrefix(filepath.Base(rel), "server-") {
+			return readCgroupLimits(filepath.Join(cgroupRoot, rel))
+		}
+	}
+	return nil
+}
+
+func readCgroupLimits(dir string) *CgroupLimits {
+	out := &CgroupLimits{Cgroup: strings.TrimPrefix(dir, cgroupRoot)}
+	if b, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		// "<quota> <period>" or "max <period>"
+		if f := strings.Fields(string(b)); len(f) == 2 && f[0] != "max" {
+			quota, _ := strconv.ParseFloat(f[0], 64)
+			period, _ := strconv.ParseFloat(f[1], 64)
+			if period > 0 {
+				out.CPUMax = quota / period
+			}
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "memory.max")); err == nil {
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64); err == nil {
+			out.MemoryMaxMB = int(v >> 20)
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "io.weight")); err == nil {
+		// "default <weight>" followed by per-device overrides
+		if f := strings.Fields(string(b)); len(f) >= 2 && f[0] == "default" {
+			out.IOWeight, _ = strconv.Atoi(f[1])
+		}
+	}
+	return out
+}
+
+// releaseCgroup removes the server's cgroup once its process has exited.
+func (s *Server) releaseCgroup(limits *CgroupLimits) {
+	if limits == nil || limits.Cgroup == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(cgroupRoot, limits.Cgroup)); err != nil && !os.IsNotExist(err) {
+		log.Debug("failed to remove server cgroup", "id", s.cfg.ID, "err", err)
+	}
+}
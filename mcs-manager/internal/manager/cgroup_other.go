@@ -0,0 +1,21 @@
+//go:build !linux
+
+package manager
+
+import (
+	"os"
+	"os/exec"
+)
+
+func (s *Server) prepareLimits() (*CgroupLimits, *os.File) {
+	if !hasLimits(s.cfg) {
+		return nil, nil
+	}
+	return &CgroupLimits{Error: "resource limits are only supported on linux"}, nil
+}
+
+func startInCgroup(cmd *exec.Cmd, cgroup *os.File) {}
+
+func processLimits(pid int) *CgroupLimits { return nil }
+
+func (s *Server) releaseCgroup(limits *CgroupLimits) {}
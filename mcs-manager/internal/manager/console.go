@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrConsoleTimeout = errors.New("timed out waiting for console output")
+	ErrServerExited   = errors.New("server exited")
+)
+
+// LineWaiter waits for a console line matching a predicate.
+// It must be registered before the command that triggers the output is sent.
+type LineWaiter struct {
+	s     *Server
+	match func(string) bool
+	ch    chan string
+	done  chan struct{}
+	once  sync.Once
+}
+
+type consoleWaiters struct {
+	mu   sync.Mutex
+	list []*LineWaiter
+}
+
+// ExpectLine registers a waiter for the next stdout/stderr line for which match returns true.
+func (s *Server) ExpectLine(match func(line string) bool) *LineWaiter {
+	w := &LineWaiter{s: s, match: match, ch: make(chan string, 1), done: make(chan struct{})}
+	s.waiters.mu.Lock()
+	s.waiters.list = append(s.waiters.list, w)
+	s.waiters.mu.Unlock()
+	return w
+}
+
+// Wait blocks until the expected line was printed, the server exits or the timeout expires.
+func (w *LineWaiter) Wait(timeout time.Duration) (string, error) {
+	defer w.Cancel()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case line := <-w.ch:
+		return line, nil
+	case <-w.done:
+		select {
+		case line := <-w.ch:
+			return line, nil
+		default:
+		}
+		return "", ErrServerExited
+	case <-t.C:
+		return "", ErrConsoleTimeout
+	}
+}
+
+// Cancel unregisters the waiter. It is safe to call multiple times.
+func (w *LineWaiter) Cancel() {
+	w.once.Do(func() {
+		w.s.waiters.mu.Lock()
+		for i, x := range w.s.waiters.list {
+			if x == w {
+				w.s.waiters.list = append(w.s.waiters.list[:i], w.s.waiters.list[i+1:]...)
+				break
+			}
+		}
+		w.s.waiters.mu.Unlock()
+		close(w.done)
+	})
+}
+
+// SendCommandAndWait sends a console command and waits for a matching output line.
+func (s *Server) SendCommandAndWait(cmd string, match func(line string) bool, timeout time.Duration) (string, error) {
+	w := s.ExpectLine(match)
+	if err := s.SendCommand(cmd); err != nil {
+		w.Cancel()
+		return "", err
+	}
+	return w.Wait(timeout)
+}
+
+// notifyWaiters hands a console line to every waiter whose predicate matches.
+func (s *Server) notifyWaiters(line string) {
+	s.waiters.mu.Lock()
+	defer s.waiters.mu.Unlock()
+	kept := s.waiters.list[:0]
+	for _, w := range s.waiters.list {
+		if w.match(line) {
+			w.ch <- line
+			continue
+		}
+		kept = append(kept, w)
+	}
+	s.waiters.list = kept
+}
+
+// releaseWaiters wakes all pending waiters, used when the process exits.
+func (s *Server) releaseWaiters() {
+	s.waiters.mu.Lock()
+	pending := append([]*LineWaiter(nil), s.waiters.list...)
+	s.waiters.mu.Unlock()
+	for _, w := range pending {
+		w.Cancel()
+	}
+}
@@ -0,0 +1,222 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/pkg/events"
+)
+
+// defaultWarnAt lists the remaining seconds at which players are warned before a delayed stop.
+var defaultWarnAt = []int{600, 300, 120, 60, 30, 10, 5, 4, 3, 2, 1}
+
+// StopOptions configures a delayed stop or restart.
+type StopOptions struct {
+	DelaySec  int    `json:"delaySec"`
+	Message   string `json:"message,omitempty"`
+	WarnAtSec []int  `json:"warnAtSec,omitempty"`
+}
+
+// PendingStop describes a running countdown, exposed in ServerInfo and events.
+type PendingStop struct {
+	Action       string    `json:"action"`
+	Message      string    `json:"message,omitempty"`
+	At           time.Time `json:"at"`
+	DelaySec     int       `json:"delaySec"`
+	RemainingSec int       `json:"remainingSec"`
+}
+
+type countdown struct {
+	mu      sync.Mutex
+	pending *PendingStop
+	cancel  chan struct{}
+}
+
+var ErrNoPendingStop = errors.New("no pending stop")
+
+// StopAfter stops the server after opts.DelaySec seconds, broadcasting countdown
+// messages in-game. A zero delay stops immediately. An existing countdown is replaced.
+func (s *Server) StopAfter(bus *events.Bus, opts StopOptions) error {
+	return s.scheduleStop(bus, "stop", opts)
+}
+
+// RestartAfter is like StopAfter but restarts the server once the countdown ends.
+func (s *Server) RestartAfter(bus *events.Bus, opts StopOptions) error {
+	return s.scheduleStop(bus, "restart", opts)
+}
+
+func (s *Server) scheduleStop(bus *events.Bus, action string, opts StopOptions) error {
+	if s.State() != StateRunning {
+		return errors.New("not running")
+	}
+	if opts.DelaySec < 0 {
+		return errors.New("delaySec must not be negative")
+	}
+	warnAt := opts.WarnAtSec
+	if len(warnAt) == 0 {
+		warnAt = defaultWarnAt
+	}
+	warnAt = append([]int(nil), warnAt...)
+	sort.Sort(sort.Reverse(sort.IntSlice(warnAt)))
+
+	var p *PendingStop
+	var cancel chan struct{}
+	if opts.DelaySec > 0 {
+		p = &PendingStop{
+			Action:   action,
+			Message:  opts.Message,
+			At:       time.Now().Add(time.Duration(opts.DelaySec) * time.Second),
+			DelaySec: opts.DelaySec,
+		}
+		cancel = make(chan struct{})
+	}
+	// Replace the running countdown in one step, so concurrent calls never
+	// leave two countdowns running
+	s.countdown.mu.Lock()
+	old, oldCancel := s.countdown.pending, s.countdown.cancel
+	s.countdown.pending, s.countdown.cancel = p, cancel
+	s.countdown.mu.Unlock()
+	if oldCancel != nil {
+		close(oldCancel)
+		s.stopCancelled(bus, old)
+	}
+
+	if opts.DelaySec == 0 {
+		return s.finishCountdown(bus, action)
+	}
+	log.Info("delayed "+action+" scheduled", "id", s.cfg.ID, "delaySec", opts.DelaySec)
+	go s.runCountdown(bus, p, cancel, warnAt)
+	return nil
+}
+
+func (s *Server) runCountdown(bus *events.Bus, p *PendingStop, cancel chan struct{}, warnAt []int) {
+	// Announce immediately, then at every configured mark below the delay
+	s.announceStop(bus, p, p.DelaySec)
+	for _, sec := range warnAt {
+		if sec >= p.DelaySec {
+			continue
+		}
+		t := time.NewTimer(time.Until(p.At.Add(-time.Duration(sec) * time.Second)))
+		select {
+		case <-cancel:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		s.announceStop(bus, p, sec)
+	}
+	t := time.NewTimer(time.Until(p.At))
+	select {
+	case <-cancel:
+		t.Stop()
+		return
+	case <-t.C:
+	}
+
+	s.countdown.mu.Lock()
+	if s.countdown.cancel != cancel {
+		s.countdown.mu.Unlock()
+		return
+	}
+	s.countdown.pending, s.countdown.cancel = nil, nil
+	s.countdown.mu.Unlock()
+
+	if err := s.finishCountdown(bus, p.Action); err != nil {
+		log.Error("delayed "+p.Action+" failed", "id", s.cfg.ID, "err", err)
+	}
+}
+
+func (s *Server) finishCountdown(bus *events.Bus, action string) error {
+	if action == "restart" {
+		return s.Restart(bus)
+	}
+	return s.Stop(bus)
+}
+
+func (s *Server) announceStop(bus *events.Bus, p *PendingStop, remaining int) {
+	verb := "stopping"
+	if p.Action == "restart" {
+		verb = "restarting"
+	}
+	base := fmt.Sprintf("Server %s in %s", verb, humanSeconds(remaining))
+	text := base
+	if p.Message != "" {
+		text = p.Message + " (" + base + ")"
+	}
+	_ = s.SendCommand("say " + text)
+	if remaining <= 30 {
+		title, _ := json.Marshal(map[string]string{"text": base, "color": "red"})
+		_ = s.SendCommand("title @a title " + string(title))
+	}
+
+	ev := *p
+	ev.RemainingSec = remaining
+	bus.Publish(events.Event{Type: "server.stop_pending", ServerID: s.cfg.ID, Data: ev})
+}
+
+// CancelStop aborts a pending delayed stop or restart.
+func (s *Server) CancelStop(bus *events.Bus) error {
+	s.countdown.mu.Lock()
+	p, cancel := s.countdown.pending, s.countdown.cancel
+	s.countdown.pending, s.countdown.cancel = nil, nil
+	s.countdown.mu.Unlock()
+	if cancel == nil {
+		return ErrNoPendingStop
+	}
+	close(cancel)
+	s.stopCancelled(bus, p)
+	return nil
+}
+
+func (s *Server) stopCancelled(bus *events.Bus, p *PendingStop) {
+	log.Info("pending "+p.Action+" cancelled", "id", s.cfg.ID)
+	if s.State() == StateRunning {
+		_ = s.SendCommand("say Scheduled " + p.Action + " cancelled")
+	}
+	bus.Publish(events.Event{Type: "server.stop_cancelled", ServerID: s.cfg.ID, Data: *p})
+}
+
+// PendingStop returns the running countdown, if any.
+func (s *Server) PendingStop() *PendingStop {
+	s.countdown.mu.Lock()
+	defer s.countdown.mu.Unlock()
+	if s.countdown.pending == nil {
+		return nil
+	}
+	p := *s.countdown.pending
+	p.RemainingSec = int(time.Until(p.At).Round(time.Second).Seconds())
+	if p.RemainingSec < 0 {
+		p.RemainingSec = 0
+	}
+	return &p
+}
+
+func humanSeconds(sec int) string {
+	switch {
+	case sec >= 60 && sec%60 == 0:
+		if sec == 60 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", sec/60)
+	case sec == 1:
+		return "1 second"
+	default:
+		return fmt.Sprintf("%d seconds", sec)
+	}
+}
+
+// clearCountdown silently drops a pending countdown, used when the process exits.
+func (s *Server) clearCountdown() {
+	s.countdown.mu.Lock()
+	defer s.countdown.mu.Unlock()
+	if s.countdown.cancel != nil {
+		close(s.countdown.cancel)
+	}
+	s.countdown.pending, s.countdown.cancel = nil, nil
+}
@@ -0,0 +1,205 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/pkg/events"
+)
+
+const (
+	defaultHookTimeout = 5 * time.Minute
+	// hookWaitDelay bounds how long output of a finished hook's leftover children is read
+	hookWaitDelay = 5 * time.Second
+)
+
+var envKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// These make the JVM pick up options that bypass the jvmArgs checks
+var javaOptionEnv = []string{"JAVA_TOOL_OPTIONS", "JDK_JAVA_OPTIONS", "_JAVA_OPTIONS"}
+
+// These make the dynamic loader inject native code into the JVM, or change
+// which binaries the server and its hooks run.
+var loaderEnvPrefixes = []string{"LD_", "DYLD_"}
+
+// validateEnv checks env and hooks. Both are admin-only: hooks are arbitrary
+// shell commands, so these checks only keep env from undermining jvmArgs.
+func validateEnv(cfg ServerConfig) error {
+	for k, v := range cfg.Env {
+		if !envKeyRe.MatchString(k) {
+			return fmt.Errorf("invalid env name %q", k)
+		}
+		for _, name := range javaOptionEnv {
+			if strings.EqualFold(k, name) {
+				return fmt.Errorf("env %s is not allowed, use jvmArgs", k)
+			}
+		}
+		upper := strings.ToUpper(k)
+		if upper == "PATH" || slices.ContainsFunc(loaderEnvPrefixes, func(p string) bool { return strings.HasPrefix(upper, p) }) {
+			return fmt.Errorf("env %s is not allowed", k)
+		}
+		if strings.ContainsRune(v, 0) {
+			return fmt.Errorf("invalid env value for %s", k)
+		}
+	}
+	if h := cfg.Hooks; h != nil {
+		if h.TimeoutSec < 0 {
+			return errors.New("hook timeout must not be negative")
+		}
+		if strings.ContainsRune(h.PreStart, 0) || strings.ContainsRune(h.PostStop, 0) {
+			return errors.New("invalid hook command")
+		}
+	}
+	return nil
+}
+
+// environ is the manager's environment with the server's Env on top.
+func (s *Server) environ() []string {
+	keys := make([]string, 0, len(s.cfg.Env))
+	for k := range s.cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := os.Environ()
+	for _, k := range keys {
+		// exec uses the last value of a duplicate key
+		env = append(env, k+"="+s.cfg.Env[k])
+	}
+	return env
+}
+
+func (s *Server) hookTimeout() time.Duration {
+	if h := s.cfg.Hooks; h != nil && h.TimeoutSec > 0 {
+		return time.Duration(h.TimeoutSec) * time.Second
+	}
+	return defaultHookTimeout
+}
+
+// runHook runs a hook command through the shell in the server directory. Its
+// output goes to mcs.log and the event stream like console output. Hooks of a
+// server never overlap, so a restart's pre-start waits for the post-stop hook.
+// Cancelling ctx kills the hook.
+func (s *Server) runHook(ctx context.Context, bus *events.Bus, name, command string, env ...string) error {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	s.hookMu.Lock()
+	defer s.hookMu.Unlock()
+
+	timeout := s.hookTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd.Dir = s.cfg.Path
+	cmd.Env = append(s.environ(),
+		"MCS_SERVER_ID="+s.cfg.ID,
+		"MCS_SERVER_NAME="+s.cfg.Name,
+		"MCS_SERVER_DIR="+s.cfg.Path,
+		"MCS_HOOK="+name,
+	)
+	cmd.Env = append(cmd.Env, env...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcess(cmd.Process.Pid) }
+	cmd.WaitDelay = hookWaitDelay
+	out := &hookOutput{s: s, bus: bus, prefix: "[" + name + " hook] "}
+	if f, err := os.OpenFile(s.logPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err == nil {
+		out.file = f
+		defer f.Close()
+	}
+	cmd.Stdout, cmd.Stderr = out, out
+
+	log.Info("running hook", "id", s.cfg.ID, "hook", name)
+	bus.Publish(events.Event{Type: "server.hook", ServerID: s.cfg.ID, Data: map[string]any{"hook": name, "status": "running"}})
+	started := time.Now()
+	err := cmd.Run()
+	out.flush()
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		err = fmt.Errorf("timed out after %s", timeout)
+	case context.Canceled:
+		err = errors.New("cancelled")
+	}
+	data := map[string]any{"hook": name, "status": "ok", "durationMs": time.Since(started).Milliseconds()}
+	if err != nil {
+		if out.last != "" {
+			err = fmt.Errorf("%w: %s", err, out.last)
+		}
+		err = fmt.Errorf("%s hook failed: %w", name, err)
+		data["status"], data["error"] = "failed", err.Error()
+		log.Error("hook failed", "id", s.cfg.ID, "hook", name, "err", err)
+	} else {
+		log.Info("hook finished", "id", s.cfg.ID, "hook", name, "duration", time.Since(started))
+	}
+	bus.Publish(events.Event{Type: "server.hook", ServerID: s.cfg.ID, Data: data})
+	return err
+}
+
+// hookOutput splits hook output into lines for mcs.log and server.log events.
+type hookOutput struct {
+	s      *Server
+	bus    *events.Bus
+	file   *os.File
+	prefix string
+	buf    []byte
+	last   string // last non-empty line, quoted in errors
+}
+
+func (o *hookOutput) Write(p []byte) (int, error) {
+	o.buf = append(o.buf, p...)
+	for {
+		i := bytes.IndexByte(o.buf, '\n')
+		if i < 0 {
+			break
+		}
+		o.line(string(bytes.TrimRight(o.buf[:i], "\r")))
+		o.buf = o.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (o *hookOutput) flush() {
+	if len(o.buf) > 0 {
+		o.line(string(o.buf))
+		o.buf = nil
+	}
+}
+
+func (o *hookOutput) line(line string) {
+	if strings.TrimSpace(line) != "" {
+		o.last = line
+	}
+	if o.file != nil {
+		_, _ = o.file.WriteString(o.prefix + line + "\n")
+	}
+	o.bus.Publish(events.Event{Type: "server.log", ServerID: o.s.cfg.ID, Data: map[string]any{"stream": "hook", "line": o.prefix + line}})
+}
+
+// waitHooks waits for a running post-stop hook, e.g. before the manager exits.
+func (s *Server) waitHooks(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.hooks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Warn("hook still running at shutdown deadline", "id", s.cfg.ID)
+	}
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jvmPreset is a tuned flag set selectable via ServerConfig.JVMPreset.
+type jvmPreset struct {
+	minJava int // oldest Java that accepts all flags
+	flags   func(memoryMB int) []string
+}
+
+var jvmPresets = map[string]jvmPreset{
+	// https://docs.papermc.io/paper/aikars-flags
+	"aikar": {minJava: 8, flags: func(memoryMB int) []string {
+		newSize, maxNewSize, region, reserve, ihop := "30", "40", "8M", "20", "15"
+		if memoryMB > 12*1024 {
+			newSize, maxNewSize, region, reserve, ihop = "40", "50", "16M", "15", "20"
+		}
+		return []string{
+			"-XX:+UseG1GC", "-XX:+ParallelRefProcEnabled", "-XX:MaxGCPauseMillis=200",
+			"-XX:+UnlockExperimentalVMOptions", "-XX:+DisableExplicitGC", "-XX:+AlwaysPreTouch",
+			"-XX:G1NewSizePercent=" + newSize, "-XX:G1MaxNewSizePercent=" + maxNewSize,
+			"-XX:G1HeapRegionSize=" + region, "-XX:G1ReservePercent=" + reserve,
+			"-XX:G1HeapWastePercent=5", "-XX:G1MixedGCCountTarget=4",
+			"-XX:InitiatingHeapOccupancyPercent=" + ihop, "-XX:G1MixedGCLiveThresholdPercent=90",
+			"-XX:G1RSetUpdatingPauseTimePercent=5", "-XX:SurvivorRatio=32",
+			"-XX:+PerfDisableSharedMem", "-XX:MaxTenuringThreshold=1",
+			"-Dusing.aikars.flags=https://mcflags.emc.gs", "-Daikars.new.flags=true",
+		}
+	}},
+	// Generational ZGC
+	"zgc": {minJava: 21, flags: func(int) []string {
+		return []string{
+			"-XX:+UseZGC", "-XX:+ZGenerational", "-XX:+AlwaysPreTouch",
+			"-XX:+DisableExplicitGC", "-XX:+PerfDisableSharedMem",
+		}
+	}},
+}
+
+// JVMPresets lists the names accepted as ServerConfig.JVMPreset.
+func JVMPresets() []string {
+	out := make([]string, 0, len(jvmPresets))
+	for name := range jvmPresets {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// allowedJVMArgs are the option forms jvmArgs may use; anything else, e.g.
+// agents, -Xrunjdwp, module paths or option files, is rejected.
+var allowedJVMArgs = []string{
+	"-XX:", "-D",
+	"-Xss", "-Xmn", "-Xlog", "-Xshare", "-Xint", "-Xmixed", "-Xbatch", "-Xcomp",
+	"-Xnoclassgc", "-Xrs", "-Xcheck:jni",
+	"--add-opens", "--add-exports", "--add-modules", "--enable-preview", "--enable-native-access",
+	"-ea", "-da", "-esa", "-dsa", "-enableassertions", "-disableassertions",
+	"-server", "-verbose:",
+}
+
+// forbiddenJVMArgs are allowed forms that can still load foreign code or run
+// commands, or that clash with what the manager sets itself.
+var forbiddenJVMArgs = []struct{ prefix, reason string }{
+	{"-XX:OnError", "running commands on JVM events is not allowed"},
+	{"-XX:OnOutOfMemoryError", "running commands on JVM events is not allowed"},
+	{"-XX:Flags", "option files are not allowed"},
+	{"-XX:VMOptionsFile", "option files are not allowed"},
+	{"-XX:AOTLibrary", "loading native code is not allowed"},
+	{"-XX:JVMCILibPath", "loading native code is not allowed"},
+	{"-XX:SharedArchiveFile", "class data archives are not allowed"},
+	{"-Djava.system.class.loader", "custom class loaders are not allowed"},
+	{"-Djava.security.manager", "custom security managers are not allowed"},
+	{"-Djava.ext.dirs", "changing the class path is not allowed"},
+	{"-Djava.endorsed.dirs", "changing the class path is not allowed"},
+	{"-Djava.class.path", "changing the class path is not allowed"},
+	{"-Djdk.module.", "changing the module path is not allowed"},
+	{"-Xmx", "use memoryMb"},
+	{"-Xms", "use minMemoryMb"},
+}
+
+// validateLaunch checks the memory settings, preset, extra arguments, env,
+// hooks and resource limits.
+func validateLaunch(cfg ServerConfig) error {
+	if cfg.MinMemoryMB < 0 || (cfg.MemoryMB > 0 && cfg.MinMemoryMB > cfg.MemoryMB) {
+		return errors.New("minMemoryMb must be between 0 and memoryMb")
+	}
+	if _, ok := jvmPresets[cfg.JVMPreset]; cfg.JVMPreset != "" && !ok {
+		return fmt.Errorf("unknown jvm preset %q, available: %s", cfg.JVMPreset, strings.Join(JVMPresets(), ", "))
+	}
+	for _, arg := range cfg.JVMArgs {
+		if err := checkArg(arg); err != nil {
+			return fmt.Errorf("invalid jvm arg %q: %w", arg, err)
+		}
+		for _, f := range forbiddenJVMArgs {
+			if strings.HasPrefix(arg, f.prefix) {
+				return fmt.Errorf("invalid jvm arg %q: %s", arg, f.reason)
+			}
+		}
+		if !slices.ContainsFunc(allowedJVMArgs, func(p string) bool { return strings.HasPrefix(arg, p) }) {
+			return fmt.Errorf("invalid jvm arg %q: only -XX:, -D and common -X options are allowed", arg)
+		}
+	}
+	for _, arg := range cfg.ServerArgs {
+		if err := checkArg(arg); err != nil {
+			return fmt.Errorf("invalid server arg %q: %w", arg, err)
+		}
+	}
+	if err := validateEnv(cfg); err != nil {
+		return err
+	}
+	return validateLimits(cfg)
+}
+
+func checkArg(arg string) error {
+	switch {
+	case strings.TrimSpace(arg) == "":
+		return errors.New("empty")
+	case strings.ContainsAny(arg, "\x00\r\n"):
+		return errors.New("contains control characters")
+	}
+	return nil
+}
+
+// launchArgs builds the java arguments: memory, preset, extra JVM args, the
+// jar and the server args.
+func (s *Server) launchArgs(jar string) []string {
+	args := []string{"-Xmx" + strconv.Itoa(s.cfg.MemoryMB) + "M"}
+	if s.cfg.MinMemoryMB > 0 {
+		args = append(args, "-Xms"+strconv.Itoa(s.cfg.MinMemoryMB)+"M")
+	}
+	if preset, ok := jvmPresets[s.cfg.JVMPreset]; ok {
+		args = append(args, preset.flags(s.cfg.MemoryMB)...)
+	}
+	args = append(args, s.cfg.JVMArgs...)
+	args = append(args, "-jar", jar, "nogui")
+	return append(args, s.cfg.ServerArgs...)
+}
@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/pkg/events"
+)
+
+// AutoStart starts every server flagged with autoStart, ordered by autoStartOrder
+// and then name. Servers that were re-adopted after a manager restart are skipped.
+func (m *Manager) AutoStart() {
+	m.mu.RLock()
+	var list []*Server
+	for _, s := range m.items {
+		if s.cfg.AutoStart {
+			list = append(list, s)
+		}
+	}
+	m.mu.RUnlock()
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].cfg.AutoStartOrder != list[j].cfg.AutoStartOrder {
+			return list[i].cfg.AutoStartOrder < list[j].cfg.AutoStartOrder
+		}
+		return list[i].cfg.Name < list[j].cfg.Name
+	})
+
+	for _, s := range list {
+		if s.State().isActive() {
+			log.Debug("server already running, skipping auto-start", "id", s.cfg.ID)
+			continue
+		}
+		if d := s.cfg.AutoStartDelaySec; d > 0 {
+			log.Debug("waiting before auto-start", "id", s.cfg.ID, "delaySec", d)
+			select {
+			case <-m.quit:
+				return
+			case <-time.After(time.Duration(d) * time.Second):
+			}
+		}
+		select {
+		case <-m.quit:
+			return
+		default:
+		}
+		log.Info("auto-starting server", "id", s.cfg.ID, "name", s.cfg.Name)
+		if err := s.Start(m.bus); err != nil {
+			log.Error("auto-start failed", "id", s.cfg.ID, "err", err)
+		}
+	}
+}
+
+// Shutdown stops the scheduler and gracefully stops all servers in parallel.
+// Servers still alive when ctx expires are killed.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.quitOnce.Do(func() { close(m.quit) })
+
+	m.mu.RLock()
+	list := make([]*Server, 0, len(m.items))
+	for _, s := range m.items {
+		list = append(list, s)
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, s := range list {
+		wg.Add(1)
+		go func(s *Server) {
+			defer wg.Done()
+			s.shutdown(ctx, m.bus)
+		}(s)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *Server) shutdown(ctx context.Context, bus *events.Bus) {
+	s.sup.cancelPending()
+	s.clearCountdown()
+	defer s.waitHooks(ctx)
+	if done := s.cancelStart(); done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+	if !s.alive() {
+		return
+	}
+	if s.State() != StateStopping {
+		if err := s.Stop(bus); err != nil {
+			log.Warn("failed to stop server during shutdown", "id", s.cfg.ID, "err", err)
+		}
+	}
+	s.mu.Lock()
+	pid, done := s.pid, s.done
+	s.mu.Unlock()
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	log.Warn("server did not stop before shutdown deadline, killing", "id", s.cfg.ID, "pid", pid)
+	if err := killProcess(pid); err != nil {
+		log.Error("failed to kill server", "id", s.cfg.ID, "pid", pid, "err", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(killGrace):
+		log.Error("server process survived SIGKILL", "id", s.cfg.ID, "pid", pid)
+	}
+}
@@ -0,0 +1,34 @@
+package manager
+
+import "errors"
+
+// CgroupLimits are the limits in effect for a server process, read back from
+// its cgroup. Error explains why configured limits could not be applied.
+type CgroupLimits struct {
+	Cgroup      string  `json:"cgroup,omitempty"`
+	CPUMax      float64 `json:"cpuMax,omitempty"`      // CPUs, 0 is unlimited
+	MemoryMaxMB int     `json:"memoryMaxMb,omitempty"` // 0 is unlimited
+	IOWeight    int     `json:"ioWeight,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+func hasLimits(cfg ServerConfig) bool {
+	return cfg.CPUMax > 0 || cfg.MemoryMaxMB > 0 || cfg.IOWeight > 0
+}
+
+func validateLimits(cfg ServerConfig) error {
+	if cfg.CPUMax < 0 {
+		return errors.New("cpuMax must not be negative")
+	}
+	if cfg.MemoryMaxMB < 0 {
+		return errors.New("memoryMaxMb must not be negative")
+	}
+	// The heap alone takes memoryMb, the JVM needs some native memory on top
+	if cfg.MemoryMaxMB > 0 && cfg.MemoryMaxMB <= cfg.MemoryMB {
+		return errors.New("memoryMaxMb must be larger than memoryMb")
+	}
+	if cfg.IOWeight != 0 && (cfg.IOWeight < 1 || cfg.IOWeight > 10000) {
+		return errors.New("ioWeight must be between 1 and 10000")
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -17,16 +18,28 @@ import (
 )
 
 type Manager struct {
-	root  string
-	mu    sync.RWMutex
-	items map[string]*Server
-	bus   *events.Bus
-	store Store
+	root     string
+	mu       sync.RWMutex
+	items    map[string]*Server
+	bus      *events.Bus
+	store    Store
+	sched    *scheduler
+	watch    *watcher
+	sessions *sessionLog
+	profiles *profileCache
+	runtimes *runtimeRegistry
+
+	quit     chan struct{} // closed by Shutdown
+	quitOnce sync.Once
 }
 
 type Store interface {
 	LoadAll() ([]ServerConfig, error)
 	SaveAll([]ServerConfig) error
+	LoadSchedules() ([]Schedule, error)
+	SaveSchedules([]Schedule) error
+	LoadWatches() ([]WatchedServer, error)
+	SaveWatches([]WatchedServer) error
 }
 
 func New(root string, bus *events.Bus, st Store) (*Manager, error) {
@@ -35,19 +48,23 @@ func New(root string, bus *events.Bus, st Store) (*Manager, error) {
 		return nil, err
 	}
 	log.Info("manager initialized", "root", root)
-	m := &Manager{root: root, items: map[string]*Server{}, bus: bus, store: st}
+	m := &Manager{root: root, items: map[string]*Server{}, bus: bus, store: st, sched: newScheduler(), watch: newWatcher(), sessions: &sessionLog{dir: filepath.Join(root, "sessions")}, profiles: newProfileCache(root), runtimes: newRuntimeRegistry(root), quit: make(chan struct{})}
 
 	// Load persisted servers
 	if servers, err := st.LoadAll(); err == nil {
 		for _, cfg := range servers {
-			s := &Server{cfg: cfg}
-			s.state.Store(StateStopped)
+			s := m.newServer(cfg)
+			s.reattach(bus)
 			m.items[cfg.ID] = s
 		}
 		log.Info("loaded persisted servers", "count", len(servers))
 	} else {
 		log.Warn("failed to load persisted servers", "err", err)
 	}
+	m.loadSchedules()
+	go m.runScheduler()
+	m.loadWatches()
+	go m.runWatcher()
 
 	return m, nil
 }
@@ -94,6 +111,12 @@ func (m *Manager) Create(cfg server.ServerConfig) (*Server, error) {
 	if cfg.Type == "" {
 		cfg.Type = TypeVanilla
 	}
+	if err := validateLaunch(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateRestartPolicy(cfg.RestartPolicy); err != nil {
+		return nil, err
+	}
 	log.Info("creating server", "id", cfg.ID, "name", cfg.Name, "type", cfg.Type, "version", cfg.Version, "port", cfg.Port, "memory", cfg.MemoryMB)
 	
 	if err := os.MkdirAll(cfg.Path, 0o755); err != nil {
@@ -104,8 +127,15 @@ func (m *Manager) Create(cfg server.ServerConfig) (*Server, error) {
 		_ = os.WriteFile(filepath.Join(cfg.Path, "eula.txt"), []byte("eula=true\n"), 0o644)
 		log.Debug("wrote eula.txt")
 	}
-	// Write server.properties with configured port
+	// Write server.properties with configured port, query on the same (UDP) port
+	// and RCON enabled on a free port
 	propsContent := "server-port=" + strconv.Itoa(cfg.Port) + "\n"
+	propsContent += "enable-query=true\nquery.port=" + strconv.Itoa(cfg.Port) + "\n"
+	if rconPort, err := util.PickFreePort(); err == nil {
+		propsContent += "enable-rcon=true\nrcon.port=" + strconv.Itoa(rconPort) + "\nrcon.password=" + util.RandID() + util.RandID() + "\n"
+	} else {
+		log.Warn("no free port for rcon, leaving it disabled", "err", err)
+	}
 	_ = os.WriteFile(filepath.Join(cfg.Path, "server.properties"), []byte(propsContent), 0o644)
 	log.Debug("wrote server.properties", "port", cfg.Port)
 	
@@ -117,8 +147,7 @@ func (m *Manager) Create(cfg server.ServerConfig) (*Server, error) {
 	}
 	log.Debug("jar ensured", "path", jarPath)
 
-	s := &Server{cfg: cfg}
-	s.state.Store(StateStopped)
+	s := m.newServer(cfg)
 	m.mu.Lock()
 	m.items[cfg.ID] = s
 	m.mu.Unlock()
@@ -128,28 +157,59 @@ func (m *Manager) Create(cfg server.ServerConfig) (*Server, error) {
 	return s, nil
 }
 
-func (m *Manager) Delete(id string) error {
+// Delete removes a stopped server. Unless force is set, the server is archived
+// first and can be brought back with RestoreDeleted.
+func (m *Manager) Delete(id string, force bool) error {
 	s, ok := m.Get(id)
 	if !ok {
 		log.Warn("server not found for deletion", "id", id)
 		return os.ErrNotExist
 	}
-	if s.State() == StateRunning {
+	if s.State().isActive() {
 		log.Warn("cannot delete running server", "id", id, "name", s.cfg.Name)
 		return errors.New("server running")
 	}
 	log.Info("deleting server", "id", id, "name", s.cfg.Name, "path", s.cfg.Path)
-	
+	// A crashed server may be waiting for an automatic restart
+	s.sup.cancelPending()
+
+	// Keep a final backup so an accidental delete can be undone
+	if _, err := os.Stat(s.cfg.Path); err == nil && !force {
+		if _, err := m.archive(s); err != nil {
+			log.Error("failed to back up server before deletion", "id", id, "err", err)
+			return fmt.Errorf("backup before deletion failed, delete with force to skip it: %w", err)
+		}
+		if err := m.saveDeletedConfig(s.cfg); err != nil {
+			log.Warn("failed to keep configuration of deleted server", "id", id, "err", err)
+		}
+	}
+
+	s.mu.Lock()
+	if s.state.isActive() {
+		// Started again while the backup was written
+		s.mu.Unlock()
+		return errors.New("server running")
+	}
+	s.removed = true
+	s.mu.Unlock()
 	m.mu.Lock()
 	delete(m.items, id)
 	m.mu.Unlock()
 	_ = os.RemoveAll(s.cfg.Path)
+	_ = os.RemoveAll(s.runDir)
 	_ = m.persist()
+	m.deleteServerSchedules(id)
 	m.bus.Publish(events.Event{Type: "server.deleted", ServerID: id})
 	log.Info("server deleted successfully", "id", id)
 	return nil
 }
 
+func (m *Manager) newServer(cfg ServerConfig) *Server {
+	s := &Server{cfg: cfg, runDir: filepath.Join(m.root, "run", cfg.ID), sessions: m.sessions, profiles: m.profiles, runtimes: m.runtimes, quit: m.quit}
+	s.state = StateStopped
+	return s
+}
+
 func (m *Manager) persist() error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
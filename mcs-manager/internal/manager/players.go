@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"obsidian/pkg/events"
+)
+
+// OnlinePlayer is a player currently connected to a managed server.
+type OnlinePlayer struct {
+	Name     string    `json:"name"`
+	UUID     string    `json:"uuid,omitempty"`
+	IP       string    `json:"ip,omitempty"`
+	JoinedAt time.Time `json:"joinedAt"`
+
+	leaveReason string // from the "lost connection" line preceding the leave
+}
+
+// Console lines of vanilla, Paper and Fabric, e.g.
+//
+//	[12:00:00] [User Authenticator #1/INFO]: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5
+//	[12:00:00] [Server thread/INFO]: Steve[/127.0.0.1:53422] logged in with entity id 123 at (0.5, 64.0, 0.5)
+//	[12:00:00] [Server thread/INFO]: Steve joined the game
+//	[12:00:00] [Server thread/INFO]: Steve lost connection: Disconnected
+//	[12:00:00] [Server thread/INFO]: Steve left the game
+//
+// Paper shortens the prefix to "[12:00:00 INFO]: ".
+var (
+	uuidRe  = regexp.MustCompile(infoPrefix + `UUID of player ([\w.]+) is ([0-9a-fA-F-]{32,36})$`)
+	loginRe = regexp.MustCompile(infoPrefix + `([\w.]+)\[/?([^\]]+)\] logged in with entity id`)
+	joinRe  = regexp.MustCompile(infoPrefix + `([\w.]+) joined the game$`)
+	lostRe  = regexp.MustCompile(infoPrefix + `([\w.]+) lost connection: (.+)$`)
+	leftRe  = regexp.MustCompile(infoPrefix + `([\w.]+) left the game$`)
+)
+
+// infoPrefix anchors a pattern to the start of an INFO line. Chat is logged as
+// "<Steve> text" after the same prefix, so players cannot forge these lines.
+const infoPrefix = `^(?:\[[^\]]+\] \[[^\]]+/INFO\]|\[\d{2}:\d{2}:\d{2} INFO\]): `
+
+// playerTracker follows joins and leaves line by line as the console is read.
+type playerTracker struct {
+	mu      sync.Mutex
+	online  map[string]*OnlinePlayer
+	pending map[string]*OnlinePlayer // seen UUID/login lines, not joined yet
+}
+
+// trackPlayers is fed every console line.
+func (s *Server) trackPlayers(bus *events.Bus, line string) {
+	t := &s.players
+	if m := uuidRe.FindStringSubmatch(line); m != nil {
+		t.mu.Lock()
+		t.pendingLocked(m[1]).UUID = m[2]
+		t.mu.Unlock()
+		// Offline UUIDs are only valid on this server, keep them out of the shared cache
+		if !s.offlineMode() {
+			s.profiles.remember(m[1], m[2])
+		}
+		return
+	}
+	if m := loginRe.FindStringSubmatch(line); m != nil {
+		ip := m[2]
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		t.mu.Lock()
+		t.pendingLocked(m[1]).IP = ip
+		t.mu.Unlock()
+		return
+	}
+	if m := joinRe.FindStringSubmatch(line); m != nil {
+		t.mu.Lock()
+		p := t.pendingLocked(m[1])
+		delete(t.pending, m[1])
+		p.JoinedAt = time.Now()
+		if p.UUID == "" {
+			p.UUID = s.knownUUID(p.Name)
+		}
+		t.online[p.Name] = p
+		joined := *p
+		t.mu.Unlock()
+		bus.Publish(events.Event{Type: "player.joined", ServerID: s.cfg.ID, Data: joined})
+		return
+	}
+	if m := lostRe.FindStringSubmatch(line); m != nil {
+		t.mu.Lock()
+		if p, ok := t.online[m[1]]; ok {
+			p.leaveReason = m[2]
+		}
+		t.mu.Unlock()
+		return
+	}
+	if m := leftRe.FindStringSubmatch(line); m != nil {
+		t.mu.Lock()
+		p, ok := t.online[m[1]]
+		delete(t.online, m[1])
+		t.mu.Unlock()
+		if ok {
+			reason := p.leaveReason
+			if reason == "" {
+				reason = "left"
+			}
+			s.publishLeft(bus, *p, reason)
+		}
+	}
+}
+
+func (t *playerTracker) pendingLocked(name string) *OnlinePlayer {
+	if t.online == nil {
+		t.online, t.pending = map[string]*OnlinePlayer{}, map[string]*OnlinePlayer{}
+	}
+	p, ok := t.pending[name]
+	if !ok {
+		p = &OnlinePlayer{Name: name}
+		t.pending[name] = p
+	}
+	return p
+}
+
+// resetPlayers drops all tracked players once the process is gone; players the
+// log did not report as leaving (e.g. after a crash) get a player.left event.
+func (s *Server) resetPlayers(bus *events.Bus) {
+	t := &s.players
+	t.mu.Lock()
+	online := t.online
+	t.online, t.pending = nil, nil
+	t.mu.Unlock()
+	for _, p := range online {
+		s.publishLeft(bus, *p, "server stopped")
+	}
+}
+
+// publishLeft ends a player's session: it is appended to the session log and
+// announced as player.left.
+func (s *Server) publishLeft(bus *events.Bus, p OnlinePlayer, reason string) {
+	now := time.Now()
+	if s.sessions != nil {
+		s.sessions.record(Session{Player: p.Name, UUID: p.UUID, ServerID: s.cfg.ID, JoinedAt: p.JoinedAt, LeftAt: now, Reason: reason})
+	}
+	bus.Publish(events.Event{Type: "player.left", ServerID: s.cfg.ID, Data: map[string]any{
+		"name":       p.Name,
+		"uuid":       p.UUID,
+		"joinedAt":   p.JoinedAt,
+		"sessionSec": int64(now.Sub(p.JoinedAt).Seconds()),
+		"reason":     reason,
+	}})
+}
+
+// OnlinePlayers returns the connected players, longest online first.
+func (s *Server) OnlinePlayers() []OnlinePlayer {
+	t := &s.players
+	t.mu.Lock()
+	out := make([]OnlinePlayer, 0, len(t.online))
+	for _, p := range t.online {
+		out = append(out, *p)
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].JoinedAt.Before(out[j].JoinedAt) })
+	return out
+}
@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestPlayerLineRegexps(t *testing.T) {
+	tests := []struct {
+		re   *regexp.Regexp
+		line string
+		want string // first group, "" for no match
+	}{
+		{uuidRe, "[12:00:00] [User Authenticator #1/INFO]: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5", "Steve"},
+		{loginRe, "[12:00:00] [Server thread/INFO]: Steve[/127.0.0.1:53422] logged in with entity id 123 at (0.5, 64.0, 0.5)", "Steve"},
+		{joinRe, "[12:00:00] [Server thread/INFO]: Steve joined the game", "Steve"},
+		{joinRe, "[12:00:00 INFO]: Steve joined the game", "Steve"},
+		{leftRe, "[12:00:00] [Server thread/INFO]: Steve left the game", "Steve"},
+		{leftRe, "[12:00:00 INFO]: Steve left the game", "Steve"},
+		{lostRe, "[12:00:00] [Server thread/INFO]: Steve lost connection: Disconnected", "Steve"},
+
+		// Chat must not forge events
+		{joinRe, "[12:00:00] [Server thread/INFO]: <Steve> ]: Alex joined the game", ""},
+		{joinRe, "[12:00:00 INFO]: <Steve> Alex joined the game", ""},
+		{leftRe, "[12:00:00] [Server thread/INFO]: <Steve> ]: Alex left the game", ""},
+		{leftRe, "[12:00:00] [Server thread/INFO]: [Steve] Alex left the game", ""},
+		{uuidRe, "[12:00:00] [Server thread/INFO]: <Steve> ]: UUID of player Alex is 069a79f4-44e9-4726-a5be-fca90e38aaf5", ""},
+		{loginRe, "[12:00:00] [Server thread/INFO]: <Steve> ]: Alex[/1.2.3.4:1] logged in with entity id 1", ""},
+		{joinRe, "[12:00:00] [Server thread/WARN]: Steve joined the game", ""},
+		{lostRe, "[12:00:00] [Server thread/INFO]: <Steve> ]: Alex lost connection: Timed out", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if m := tt.re.FindStringSubmatch(tt.line); m != nil {
+			got = m[1]
+		}
+		if got != tt.want {
+			t.Errorf("%s on %q = %q, want %q", tt.re, tt.line, got, tt.want)
+		}
+	}
+}
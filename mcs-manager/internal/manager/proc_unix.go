@@ -0,0 +1,74 @@
+//go:build !windows
+
+package manager
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts the server in its own process group so signals reach
+// the JVM and anything it spawned, and a Ctrl+C on the manager does not.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func terminateProcess(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGTERM)
+}
+
+func killProcess(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// processMatches guards against PID reuse when re-adopting a server after a
+// manager restart. Without /proc (e.g. macOS) the PID is trusted.
+func processMatches(pid int, jar string, startedAt time.Time) bool {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err != nil {
+		return os.IsNotExist(err) && !dirExists("/proc/self")
+	}
+	return strings.Contains(string(b), jar)
+}
+
+func dirExists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// openConsoleFIFO creates a named pipe used as the server's stdin. It is
+// opened read-write so that neither side ever sees EOF, which lets a restarted
+// manager reopen it and keep sending commands to the same process.
+func openConsoleFIFO(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(path)
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+// reopenConsoleFIFO attaches to the named pipe of an already running server.
+func reopenConsoleFIFO(path string) (*os.File, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		return nil, errors.New("console is not a named pipe: " + path)
+	}
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
@@ -0,0 +1,68 @@
+//go:build windows
+
+package manager
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// Windows has no SIGTERM, so termination is always forceful
+func terminateProcess(pid int) error {
+	return killProcess(pid)
+}
+
+func killProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
+
+// processMatches guards against PID reuse when re-adopting a server after a
+// manager restart. Windows hands out PIDs again quickly and another process's
+// command line is hard to read, so the process must have been created when
+// the server was started.
+func processMatches(pid int, jar string, startedAt time.Time) bool {
+	const processQueryLimitedInformation = 0x1000
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var created, exited, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &created, &exited, &kernel, &user); err != nil {
+		return false
+	}
+	d := startedAt.Sub(time.Unix(0, created.Nanoseconds()))
+	return d > -time.Second && d < startTimeSlack
+}
+
+var errNoFIFO = errors.New("named pipe consoles are not supported on windows")
+
+// Windows has no named pipes in the filesystem, so the console falls back to a
+// regular stdin pipe and cannot be reattached after a manager restart.
+func openConsoleFIFO(path string) (*os.File, error) {
+	return nil, errNoFIFO
+}
+
+func reopenConsoleFIFO(path string) (*os.File, error) {
+	return nil, errNoFIFO
+}
@@ -0,0 +1,223 @@
+package manager
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/util"
+)
+
+const (
+	DefaultProfileAPI = "https://api.mojang.com"
+	// Minecraft keeps usercache.json entries for a month as well
+	DefaultProfileTTL = 30 * 24 * time.Hour
+	profileTimeout    = 10 * time.Second
+)
+
+var ErrUnknownPlayer = errors.New("unknown player")
+
+// profileCache resolves player names to UUIDs via a Mojang compatible profile
+// API and caches the results in <root>/usercache.json, in the same format the
+// server uses for its own cache.
+type profileCache struct {
+	mu      sync.Mutex
+	file    string
+	baseURL string
+	ttl     time.Duration
+	client  *http.Client
+	entries map[string]cachedProfile // by lower-case name
+}
+
+type cachedProfile struct {
+	Name      string `json:"name"`
+	UUID      string `json:"uuid"`
+	ExpiresOn string `json:"expiresOn"`
+}
+
+func newProfileCache(root string) *profileCache {
+	c := &profileCache{
+		file:    filepath.Join(root, "usercache.json"),
+		baseURL: DefaultProfileAPI,
+		ttl:     DefaultProfileTTL,
+		client:  &http.Client{Timeout: profileTimeout},
+		entries: map[string]cachedProfile{},
+	}
+	var list []cachedProfile
+	if b, err := os.ReadFile(c.file); err == nil {
+		if err := json.Unmarshal(b, &list); err != nil {
+			log.Warn("failed to parse profile cache", "path", c.file, "err", err)
+		}
+	}
+	for _, p := range list {
+		c.entries[strings.ToLower(p.Name)] = p
+	}
+	return c
+}
+
+// SetProfileAPI points name lookups at another profile API base URL and sets
+// how long resolved profiles are cached. Empty or zero values keep the defaults.
+func (m *Manager) SetProfileAPI(baseURL string, ttl time.Duration) {
+	m.profiles.mu.Lock()
+	defer m.profiles.mu.Unlock()
+	if baseURL != "" {
+		m.profiles.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	if ttl > 0 {
+		m.profiles.ttl = ttl
+	}
+}
+
+// cached returns a profile that has not expired yet.
+func (c *profileCache) cached(name string) (cachedProfile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.entries[strings.ToLower(name)]
+	if !ok {
+		return p, false
+	}
+	exp, err := time.Parse(mcTimeLayout, p.ExpiresOn)
+	return p, err == nil && time.Now().Before(exp)
+}
+
+// lookup resolves a name from the cache or, on a miss, the profile API.
+func (c *profileCache) lookup(name string) (string, string, error) {
+	if p, ok := c.cached(name); ok {
+		return p.UUID, p.Name, nil
+	}
+	c.mu.Lock()
+	endpoint := c.baseURL + "/users/profiles/minecraft/" + url.PathEscape(name)
+	c.mu.Unlock()
+
+	resp, err := c.client.Get(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == 404 || resp.StatusCode == 204:
+		return "", "", fmt.Errorf("%w: %s", ErrUnknownPlayer, name)
+	case resp.StatusCode != 200:
+		return "", "", fmt.Errorf("profile lookup failed: %s", resp.Status)
+	}
+	var p struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return "", "", err
+	}
+	id, err := dashUUID(p.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("profile lookup returned invalid id %q", p.ID)
+	}
+	c.remember(p.Name, id)
+	return id, p.Name, nil
+}
+
+// remember stores a profile, e.g. one the server printed while a player logged in.
+func (c *profileCache) remember(name, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[strings.ToLower(name)] = cachedProfile{Name: name, UUID: id, ExpiresOn: time.Now().Add(c.ttl).Format(mcTimeLayout)}
+	if err := c.persistLocked(); err != nil {
+		log.Warn("failed to write profile cache", "path", c.file, "err", err)
+	}
+}
+
+// persistLocked writes the unexpired entries, most recently added first like Minecraft.
+func (c *profileCache) persistLocked() error {
+	now := time.Now()
+	list := make([]cachedProfile, 0, len(c.entries))
+	for key, p := range c.entries {
+		if exp, err := time.Parse(mcTimeLayout, p.ExpiresOn); err != nil || now.After(exp) {
+			delete(c.entries, key)
+			continue
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ExpiresOn > list[j].ExpiresOn })
+	b, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	tmp := c.file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.file)
+}
+
+// OfflineUUID is the UUID an offline-mode server assigns to a name: a version 3
+// UUID of the MD5 hash of "OfflinePlayer:<name>". Names are case-sensitive.
+func OfflineUUID(name string) string {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+	id, _ := dashUUID(hex.EncodeToString(sum[:]))
+	return id
+}
+
+// dashUUID formats a 32 digit hex id as 8-4-4-4-12.
+func dashUUID(id string) (string, error) {
+	if len(id) != 32 {
+		return "", errors.New("invalid uuid")
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", errors.New("invalid uuid")
+	}
+	return strings.ToLower(id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:]), nil
+}
+
+// offlineMode reports whether the server runs with online-mode=false.
+func (s *Server) offlineMode() bool {
+	props, err := util.ParseProperties(filepath.Join(s.cfg.Path, "server.properties"))
+	return err == nil && strings.EqualFold(props["online-mode"], "false")
+}
+
+// lookupProfile finds the UUID and name of a player the way this server sees
+// them: derived from the name in offline mode, otherwise from the server's own
+// usercache.json, the players online right now or the shared profile cache.
+func (s *Server) lookupProfile(name string) (string, string, error) {
+	if s.offlineMode() {
+		return OfflineUUID(name), name, nil
+	}
+	var cache []cachedProfile
+	if b, err := os.ReadFile(filepath.Join(s.cfg.Path, "usercache.json")); err == nil {
+		_ = json.Unmarshal(b, &cache)
+	}
+	for _, c := range cache {
+		if strings.EqualFold(c.Name, name) && c.UUID != "" {
+			return c.UUID, c.Name, nil
+		}
+	}
+	for _, p := range s.OnlinePlayers() {
+		if strings.EqualFold(p.Name, name) && p.UUID != "" {
+			return p.UUID, p.Name, nil
+		}
+	}
+	return s.profiles.lookup(name)
+}
+
+// knownUUID resolves a name without network access, for use on the console path.
+func (s *Server) knownUUID(name string) string {
+	if s.offlineMode() {
+		return OfflineUUID(name)
+	}
+	if p, ok := s.profiles.cached(name); ok {
+		return p.UUID
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOfflineUUID(t *testing.T) {
+	if got, want := OfflineUUID("Notch"), "b50ad385-829d-3141-a216-7e7d7539ba7f"; got != want {
+		t.Errorf("OfflineUUID(Notch) = %s, want %s", got, want)
+	}
+	// Offline UUIDs depend on the exact spelling
+	if OfflineUUID("notch") == OfflineUUID("Notch") {
+		t.Error("offline UUIDs must be case-sensitive")
+	}
+}
+
+func TestDashUUID(t *testing.T) {
+	got, err := dashUUID("853C80EF3C3749FDAA49938B674ADAE6")
+	if err != nil || got != "853c80ef-3c37-49fd-aa49-938b674adae6" {
+		t.Errorf("dashUUID = %q, %v", got, err)
+	}
+	for _, bad := range []string{"", "853c80ef3c3749fd", "853c80ef-3c37-49fd-aa49-938b674adae6", "zzzc80ef3c3749fdaa49938b674adae6"} {
+		if _, err := dashUUID(bad); err == nil {
+			t.Errorf("dashUUID(%q): expected an error", bad)
+		}
+	}
+}
+
+// profileAPI is a stand-in for the Mojang profile API that knows jeb_.
+func profileAPI(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch strings.TrimPrefix(r.URL.Path, "/users/profiles/minecraft/") {
+		case "jeb_", "JEB_":
+			w.Write([]byte(`{"id":"853c80ef3c3749fdaa49938b674adae6","name":"jeb_"}`))
+		case "Renamed":
+			// Older API versions answer unknown names with 204
+			w.WriteHeader(204)
+		case "Broken":
+			w.Write([]byte(`{"id":"nope","name":"Broken"}`))
+		case "Flaky":
+			w.WriteHeader(500)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestProfileLookup(t *testing.T) {
+	srv, calls := profileAPI(t)
+	root := t.TempDir()
+	c := newProfileCache(root)
+	c.baseURL = srv.URL
+
+	id, name, err := c.lookup("JEB_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "853c80ef-3c37-49fd-aa49-938b674adae6" || name != "jeb_" {
+		t.Errorf("lookup = %s %s", id, name)
+	}
+	// The second lookup is answered from the cache
+	if _, _, err := c.lookup("jeb_"); err != nil || calls.Load() != 1 {
+		t.Errorf("cached lookup: err %v, %d API calls", err, calls.Load())
+	}
+
+	for _, unknown := range []string{"Renamed", "Nobody"} {
+		if _, _, err := c.lookup(unknown); !errors.Is(err, ErrUnknownPlayer) {
+			t.Errorf("lookup(%s) = %v, want ErrUnknownPlayer", unknown, err)
+		}
+	}
+	for _, bad := range []string{"Broken", "Flaky"} {
+		if _, _, err := c.lookup(bad); err == nil || errors.Is(err, ErrUnknownPlayer) {
+			t.Errorf("lookup(%s) = %v, want a lookup error", bad, err)
+		}
+	}
+
+	// usercache.json uses Minecraft's format and survives a restart
+	var list []cachedProfile
+	b, err := os.ReadFile(filepath.Join(root, "usercache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &list); err != nil || len(list) != 1 || list[0].Name != "jeb_" {
+		t.Fatalf("usercache.json = %s", b)
+	}
+	if _, err := time.Parse(mcTimeLayout, list[0].ExpiresOn); err != nil {
+		t.Errorf("expiresOn: %v", err)
+	}
+	if p, ok := newProfileCache(root).cached("Jeb_"); !ok || p.UUID != id {
+		t.Errorf("reloaded cache = %+v, %v", p, ok)
+	}
+}
+
+func TestProfileCacheTTL(t *testing.T) {
+	srv, calls := profileAPI(t)
+	c := newProfileCache(t.TempDir())
+	c.baseURL = srv.URL
+	c.ttl = time.Hour
+
+	c.remember("Alex", "ec561538-f3fd-461d-aff5-086b22154bce")
+	if p, ok := c.cached("alex"); !ok || p.Name != "Alex" {
+		t.Fatalf("cached = %+v, %v", p, ok)
+	}
+	exp, _ := time.Parse(mcTimeLayout, c.entries["alex"].ExpiresOn)
+	if d := time.Until(exp); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("entry expires in %s, want the 1h ttl", d)
+	}
+
+	// Expired entries are looked up again and dropped from the file
+	c.entries["jeb_"] = cachedProfile{Name: "jeb_", UUID: "stale", ExpiresOn: time.Now().Add(-time.Minute).Format(mcTimeLayout)}
+	if _, ok := c.cached("jeb_"); ok {
+		t.Error("expired entry reported as cached")
+	}
+	id, _, err := c.lookup("jeb_")
+	if err != nil || id == "stale" || calls.Load() != 1 {
+		t.Errorf("lookup after expiry = %q, %v, %d API calls", id, err, calls.Load())
+	}
+
+	c.entries["old"] = cachedProfile{Name: "Old", UUID: "x", ExpiresOn: time.Now().Add(-time.Hour).Format(mcTimeLayout)}
+	c.remember("Steve", "8667ba71-b85a-4004-af54-457a9734eed7")
+	if _, ok := c.entries["old"]; ok {
+		t.Error("expired entry kept after persisting")
+	}
+}
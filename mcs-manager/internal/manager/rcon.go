@@ -0,0 +1,73 @@
+package manager
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/rcon"
+	"obsidian/internal/util"
+)
+
+const rconTimeout = 10 * time.Second
+
+var ErrRconDisabled = errors.New("rcon is not enabled for this server")
+
+// rconConn caches the RCON connection of a server between commands.
+type rconConn struct {
+	mu     sync.Mutex
+	client *rcon.Client
+}
+
+// Exec runs a command over RCON and returns its output. It only relies on the
+// RCON settings in server.properties, so it also works for a server process the
+// manager did not start.
+func (s *Server) Exec(cmd string) (string, error) {
+	s.rcon.mu.Lock()
+	defer s.rcon.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if s.rcon.client == nil {
+			c, err := s.dialRcon()
+			if err != nil {
+				return "", err
+			}
+			s.rcon.client = c
+		}
+		log.Debug("executing rcon command", "id", s.cfg.ID, "cmd", cmd)
+		out, err := s.rcon.client.Exec(cmd)
+		if err == nil {
+			return out, nil
+		}
+		_ = s.rcon.client.Close()
+		s.rcon.client = nil
+		// A cached connection goes stale when the server restarts, so reconnect once
+		if attempt > 0 || errors.Is(err, rcon.ErrCommandTooLong) {
+			return "", err
+		}
+		log.Debug("rcon command failed, reconnecting", "id", s.cfg.ID, "err", err)
+	}
+}
+
+func (s *Server) dialRcon() (*rcon.Client, error) {
+	props, err := util.ParseProperties(filepath.Join(s.cfg.Path, "server.properties"))
+	if err != nil {
+		return nil, err
+	}
+	if props["enable-rcon"] != "true" || props["rcon.port"] == "" || props["rcon.password"] == "" {
+		return nil, ErrRconDisabled
+	}
+	return rcon.Dial(net.JoinHostPort("127.0.0.1", props["rcon.port"]), props["rcon.password"], rconTimeout)
+}
+
+func (s *Server) closeRcon() {
+	s.rcon.mu.Lock()
+	defer s.rcon.mu.Unlock()
+	if s.rcon.client != nil {
+		_ = s.rcon.client.Close()
+		s.rcon.client = nil
+	}
+}
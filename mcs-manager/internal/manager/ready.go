@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/query"
+	"obsidian/pkg/events"
+)
+
+const (
+	defaultStartTimeout = 5 * time.Minute
+	readyPingInterval   = 2 * time.Second
+)
+
+// doneRe matches the line vanilla, Paper and Fabric print once the world is loaded:
+// [12:00:00] [Server thread/INFO]: Done (12.345s)! For help, type "help"
+// but not a player typing it in chat.
+var doneRe = regexp.MustCompile(infoPrefix + `Done \(\d+[.,]\d+s\)! For help, type "help"`)
+
+func (s *Server) startTimeout() time.Duration {
+	if s.cfg.StartTimeoutSec > 0 {
+		return time.Duration(s.cfg.StartTimeoutSec) * time.Second
+	}
+	return defaultStartTimeout
+}
+
+// checkReadyLine is fed every console line while the server is starting.
+func (s *Server) checkReadyLine(bus *events.Bus, line string) {
+	if s.State() == StateStarting && doneRe.MatchString(line) {
+		s.markReady(bus, "log")
+	}
+}
+
+// markReady moves a starting server to running exactly once per start.
+func (s *Server) markReady(bus *events.Bus, via string) {
+	s.mu.Lock()
+	if s.state != StateStarting || s.setStateLocked(bus, StateRunning) != nil {
+		s.mu.Unlock()
+		return
+	}
+	d := time.Since(s.startAt)
+	s.startupDuration = d
+	done := s.done
+	s.mu.Unlock()
+	go s.pollStatus(bus, done)
+	log.Info("server is ready", "id", s.cfg.ID, "name", s.cfg.Name, "startup", d.Round(time.Millisecond), "via", via)
+	bus.Publish(events.Event{Type: "server.ready", ServerID: s.cfg.ID, Data: map[string]any{
+		"startupDurationMs": d.Milliseconds(),
+	}})
+}
+
+// awaitReady falls back to status pings for servers whose log output does not
+// contain the usual "Done" line, and fails the start once the timeout expires.
+func (s *Server) awaitReady(bus *events.Bus, done <-chan struct{}) {
+	deadline := time.NewTimer(s.startTimeout())
+	defer deadline.Stop()
+	ticker := time.NewTicker(readyPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if s.State() != StateStarting {
+				return
+			}
+			if _, err := query.PingServer("localhost", s.cfg.Port, time.Second); err == nil {
+				s.markReady(bus, "ping")
+				return
+			}
+		case <-deadline.C:
+			reason := fmt.Sprintf("server did not become ready within %s", s.startTimeout())
+			s.mu.Lock()
+			if s.state != StateStarting {
+				s.mu.Unlock()
+				return
+			}
+			s.failReason = reason
+			pid := s.pid
+			s.mu.Unlock()
+			log.Error("server start timed out, killing process", "id", s.cfg.ID, "timeout", s.startTimeout())
+			bus.Publish(events.Event{Type: "server.start_failed", ServerID: s.cfg.ID, Data: map[string]any{"error": reason}})
+			if err := killProcess(pid); err != nil {
+				log.Error("failed to kill server", "id", s.cfg.ID, "pid", pid, "err", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package manager
+
+import "testing"
+
+func TestDoneRe(t *testing.T) {
+	for line, want := range map[string]bool{
+		`[12:00:00] [Server thread/INFO]: Done (12.345s)! For help, type "help"`:                   true,
+		`[12:00:00 INFO]: Done (3,210s)! For help, type "help"`:                                    true,
+		`[12:00:00] [Server thread/INFO]: <Steve> Done (1.0s)! For help, type "help"`:              false,
+		`[12:00:00] [Server thread/INFO]: <Steve> ]: Done (1.0s)! For help, type "help"`:           false,
+		`[12:00:00] [Server thread/INFO]: [Not Secure] <Steve> Done (1.0s)! For help, type "help"`: false,
+	} {
+		if got := doneRe.MatchString(line); got != want {
+			t.Errorf("doneRe on %q = %v", line, got)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package manager
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const probeTimeout = 10 * time.Second
+
+// JavaRuntime is an installed JDK or JRE.
+type JavaRuntime struct {
+	Path    string `json:"path"`
+	Version string `json:"version"` // as printed by java -version, e.g. "21.0.2" or "1.8.0_392"
+	Major   int    `json:"major"`
+	// Source is where it was found: JAVA_HOME, PATH, managed (<root>/runtimes) or system
+	Source string `json:"source"`
+}
+
+// JavaMismatchError is returned when server.jar needs a newer Java than the selected runtime.
+type JavaMismatchError struct {
+	Required int
+	Runtime  JavaRuntime
+}
+
+func (e *JavaMismatchError) Error() string {
+	return fmt.Sprintf("server.jar requires Java %d but %s is Java %d", e.Required, e.Runtime.Path, e.Runtime.Major)
+}
+
+// runtimeRegistry discovers Java installations once and remembers every probed binary.
+type runtimeRegistry struct {
+	mu      sync.Mutex
+	dir     string
+	list    []JavaRuntime
+	scanned bool
+	probed  map[string]JavaRuntime // by resolved path
+}
+
+func newRuntimeRegistry(root string) *runtimeRegistry {
+	return &runtimeRegistry{dir: filepath.Join(root, "runtimes"), probed: map[string]JavaRuntime{}}
+}
+
+// systemJavaGlobs are the usual install locations on Linux (and macOS).
+var systemJavaGlobs = []string{
+	"/usr/lib/jvm/*/bin/java",
+	"/usr/lib64/jvm/*/bin/java",
+	"/usr/java/*/bin/java",
+	"/opt/java/*/bin/java",
+	"/opt/jdk*/bin/java",
+	"/Library/Java/JavaVirtualMachines/*/Contents/Home/bin/java",
+}
+
+func javaBinary() string {
+	if runtime.GOOS == "windows" {
+		return "java.exe"
+	}
+	return "java"
+}
+
+// Runtimes lists the discovered Java runtimes, newest first. refresh rescans
+// and re-probes all candidates, e.g. after installing a JDK.
+func (m *Manager) Runtimes(refresh bool) []JavaRuntime {
+	return m.runtimes.all(refresh)
+}
+
+func (r *runtimeRegistry) all(refresh bool) []JavaRuntime {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.scanned && !refresh {
+		return append([]JavaRuntime(nil), r.list...)
+	}
+	if refresh {
+		r.probed = map[string]JavaRuntime{}
+	}
+
+	type candidate struct{ path, source string }
+	var cands []candidate
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		cands = append(cands, candidate{filepath.Join(home, "bin", javaBinary()), "JAVA_HOME"})
+	}
+	for _, pattern := range []string{
+		filepath.Join(r.dir, "*", "bin", javaBinary()),
+		filepath.Join(r.dir, "*", "Contents", "Home", "bin", javaBinary()),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		for _, p := range matches {
+			cands = append(cands, candidate{p, "managed"})
+		}
+	}
+	for _, pattern := range systemJavaGlobs {
+		matches, _ := filepath.Glob(pattern)
+		for _, p := range matches {
+			cands = append(cands, candidate{p, "system"})
+		}
+	}
+	if p, err := exec.LookPath(javaBinary()); err == nil {
+		cands = append(cands, candidate{p, "PATH"})
+	}
+
+	seen := map[string]bool{}
+	r.list = r.list[:0]
+	for _, c := range cands {
+		rt, err := r.probeLocked(c.path)
+		if err != nil {
+			log.Debug("skipping java candidate", "path", c.path, "err", err)
+			continue
+		}
+		real := realPath(c.path)
+		if seen[real] {
+			continue
+		}
+		seen[real] = true
+		rt.Source = c.source
+		r.list = append(r.list, rt)
+	}
+	sort.SliceStable(r.list, func(i, j int) bool { return r.list[i].Major > r.list[j].Major })
+	r.scanned = true
+	log.Info("discovered java runtimes", "count", len(r.list))
+	return append([]JavaRuntime(nil), r.list...)
+}
+
+func realPath(p string) string {
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		return real
+	}
+	return p
+}
+
+func (r *runtimeRegistry) probe(path string) (JavaRuntime, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.probeLocked(path)
+}
+
+func (r *runtimeRegistry) probeLocked(path string) (JavaRuntime, error) {
+	real := realPath(path)
+	if rt, ok := r.probed[real]; ok {
+		rt.Path = path
+		return rt, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	// java -version prints to stderr
+	out, err := exec.CommandContext(ctx, path, "-version").CombinedOutput()
+	if err != nil {
+		return JavaRuntime{}, err
+	}
+	rt, err := parseJavaVersion(string(out))
+	if err != nil {
+		return JavaRuntime{}, err
+	}
+	rt.Path = path
+	r.probed[real] = rt
+	return rt, nil
+}
+
+// e.g. `openjdk version "21.0.2" 2024-01-16` or `java version "1.8.0_392"`
+var javaVersionRe = regexp.MustCompile(`version "([^"]+)"`)
+
+func parseJavaVersion(out string) (JavaRuntime, error) {
+	m := javaVersionRe.FindStringSubmatch(out)
+	if m == nil {
+		return JavaRuntime{}, fmt.Errorf("unrecognized java -version output: %q", firstLine(out))
+	}
+	v := m[1]
+	// Before Java 9 the major version came second: 1.8.0 is Java 8
+	trimmed := strings.TrimPrefix(v, "1.")
+	end := strings.IndexFunc(trimmed, func(r rune) bool { return r < '0' || r > '9' })
+	if end < 0 {
+		end = len(trimmed)
+	}
+	major, err := strconv.Atoi(trimmed[:end])
+	if err != nil {
+		return JavaRuntime{}, fmt.Errorf("unrecognized java version %q", v)
+	}
+	return JavaRuntime{Version: v, Major: major}, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// selectJava picks the binary to run: JavaPath if set, otherwise a discovered
+// runtime of JavaVersion, otherwise java from PATH. It fails when server.jar
+// or the JVM preset needs a newer Java than the selected one.
+func (s *Server) selectJava() (string, error) {
+	var (
+		java = javaBinary()
+		rt   JavaRuntime
+		err  error
+	)
+	switch {
+	case s.cfg.JavaPath != "":
+		java = s.cfg.JavaPath
+		if rt, err = s.runtimes.probe(java); err != nil {
+			return "", fmt.Errorf("java runtime %s: %w", java, err)
+		}
+	case s.cfg.JavaVersion != 0:
+		for _, c := range s.runtimes.all(false) {
+			if c.Major == s.cfg.JavaVersion {
+				rt = c
+				break
+			}
+		}
+		if rt.Path == "" {
+			return "", fmt.Errorf("no Java %d runtime found, install one or place it in %s", s.cfg.JavaVersion, s.runtimes.dir)
+		}
+		java = rt.Path
+	default:
+		path, err := exec.LookPath(java)
+		if err != nil {
+			// Let the start fail the usual way
+			return java, nil
+		}
+		if rt, err = s.runtimes.probe(path); err != nil {
+			log.Debug("could not determine java version", "path", path, "err", err)
+			return java, nil
+		}
+	}
+
+	if preset, ok := jvmPresets[s.cfg.JVMPreset]; ok && rt.Major < preset.minJava {
+		return "", fmt.Errorf("jvm preset %s needs Java %d or newer but %s is Java %d", s.cfg.JVMPreset, preset.minJava, rt.Path, rt.Major)
+	}
+	required, err := jarJavaVersion(s.jarPath())
+	if err != nil {
+		log.Debug("could not determine required java version", "id", s.cfg.ID, "err", err)
+		return java, nil
+	}
+	if required > rt.Major {
+		return "", &JavaMismatchError{Required: required, Runtime: rt}
+	}
+	return java, nil
+}
+
+// jarJavaVersion returns the Java major version a server jar needs: from the
+// java_version of vanilla's version.json, or else the class-file version of
+// its Main-Class.
+func jarJavaVersion(jar string) (int, error) {
+	zr, err := zip.OpenReader(jar)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	if f, err := zr.Open("version.json"); err == nil {
+		var v struct {
+			JavaVersion int `json:"java_version"`
+		}
+		err := json.NewDecoder(f).Decode(&v)
+		f.Close()
+		if err == nil && v.JavaVersion > 0 {
+			return v.JavaVersion, nil
+		}
+	}
+
+	mf, err := zr.Open("META-INF/MANIFEST.MF")
+	if err != nil {
+		return 0, err
+	}
+	var mainClass string
+	sc := bufio.NewScanner(mf)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), "Main-Class:"); ok {
+			mainClass = strings.TrimSpace(v)
+			break
+		}
+	}
+	mf.Close()
+	if mainClass == "" {
+		return 0, fmt.Errorf("no Main-Class in %s", jar)
+	}
+	cf, err := zr.Open(strings.ReplaceAll(mainClass, ".", "/") + ".class")
+	if err != nil {
+		return 0, err
+	}
+	defer cf.Close()
+	// u4 magic, u2 minor_version, u2 major_version
+	var hdr [8]byte
+	if _, err := io.ReadFull(cf, hdr[:]); err != nil {
+		return 0, err
+	}
+	if binary.BigEndian.Uint32(hdr[:4]) != 0xCAFEBABE {
+		return 0, fmt.Errorf("%s is not a class file", mainClass)
+	}
+	// Class-file version 52 is Java 8, 65 is Java 21
+	return int(binary.BigEndian.Uint16(hdr[6:])) - 44, nil
+}
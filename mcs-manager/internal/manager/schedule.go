@@ -0,0 +1,303 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/cron"
+	"obsidian/internal/util"
+	"obsidian/pkg/events"
+)
+
+type ScheduleAction string
+
+const (
+	ActionStart   ScheduleAction = "start"
+	ActionStop    ScheduleAction = "stop"
+	ActionRestart ScheduleAction = "restart"
+	ActionCommand ScheduleAction = "command"
+	ActionBackup  ScheduleAction = "backup"
+)
+
+// Schedule is a persisted cron job bound to a single server.
+type Schedule struct {
+	ID        string         `json:"id"`
+	ServerID  string         `json:"serverId"`
+	Name      string         `json:"name"`
+	Cron      string         `json:"cron"`
+	Action    ScheduleAction `json:"action"`
+	Command   string         `json:"command,omitempty"`
+	DelaySec  int            `json:"delaySec,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Enabled   bool           `json:"enabled"`
+	LastRun   *time.Time     `json:"lastRun,omitempty"`
+	LastError string         `json:"lastError,omitempty"`
+	NextRun   *time.Time     `json:"nextRun,omitempty"`
+}
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+type scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*Schedule
+	parsed  map[string]*cron.Schedule
+	running map[string]bool
+	saveMu  sync.Mutex // serializes persistSchedules, jobs may finish at the same time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{jobs: map[string]*Schedule{}, parsed: map[string]*cron.Schedule{}, running: map[string]bool{}}
+}
+
+func (sc *Schedule) validate() (*cron.Schedule, error) {
+	switch sc.Action {
+	case ActionStart, ActionStop, ActionRestart, ActionBackup:
+	case ActionCommand:
+		if sc.Command == "" {
+			return nil, errors.New("command required for command action")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported schedule action: %q", sc.Action)
+	}
+	return cron.Parse(sc.Cron)
+}
+
+func (sch *scheduler) put(sc *Schedule, cs *cron.Schedule, now time.Time) {
+	sch.jobs[sc.ID] = sc
+	sch.parsed[sc.ID] = cs
+	sc.NextRun = nil
+	if sc.Enabled {
+		if next := cs.Next(now); !next.IsZero() {
+			sc.NextRun = &next
+		}
+	}
+}
+
+// loadSchedules restores persisted jobs. Invalid entries are kept but disabled.
+func (m *Manager) loadSchedules() {
+	list, err := m.store.LoadSchedules()
+	if err != nil {
+		log.Warn("failed to load schedules", "err", err)
+		return
+	}
+	now := time.Now()
+	m.sched.mu.Lock()
+	defer m.sched.mu.Unlock()
+	for i := range list {
+		sc := list[i]
+		cs, err := sc.validate()
+		if err != nil {
+			log.Warn("disabling invalid schedule", "id", sc.ID, "server", sc.ServerID, "err", err)
+			sc.Enabled = false
+			sc.LastError = err.Error()
+			m.sched.jobs[sc.ID] = &sc
+			continue
+		}
+		m.sched.put(&sc, cs, now)
+	}
+	log.Info("loaded schedules", "count", len(list))
+}
+
+func (m *Manager) persistSchedules() error {
+	// Held across snapshot and write, so the newest snapshot is written last
+	m.sched.saveMu.Lock()
+	defer m.sched.saveMu.Unlock()
+	m.sched.mu.Lock()
+	arr := make([]Schedule, 0, len(m.sched.jobs))
+	for _, sc := range m.sched.jobs {
+		arr = append(arr, *sc)
+	}
+	m.sched.mu.Unlock()
+	sort.Slice(arr, func(i, j int) bool { return arr[i].ID < arr[j].ID })
+	return m.store.SaveSchedules(arr)
+}
+
+func (m *Manager) ListSchedules(serverID string) ([]Schedule, error) {
+	if _, ok := m.Get(serverID); !ok {
+		return nil, os.ErrNotExist
+	}
+	m.sched.mu.Lock()
+	defer m.sched.mu.Unlock()
+	out := []Schedule{}
+	for _, sc := range m.sched.jobs {
+		if sc.ServerID == serverID {
+			out = append(out, *sc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (m *Manager) GetSchedule(serverID, id string) (Schedule, error) {
+	m.sched.mu.Lock()
+	defer m.sched.mu.Unlock()
+	sc, ok := m.sched.jobs[id]
+	if !ok || sc.ServerID != serverID {
+		return Schedule{}, ErrScheduleNotFound
+	}
+	return *sc, nil
+}
+
+func (m *Manager) CreateSchedule(serverID string, sc Schedule) (Schedule, error) {
+	if _, ok := m.Get(serverID); !ok {
+		return Schedule{}, os.ErrNotExist
+	}
+	cs, err := sc.validate()
+	if err != nil {
+		return Schedule{}, err
+	}
+	sc.ID = util.RandID()
+	sc.ServerID = serverID
+	sc.LastRun, sc.LastError = nil, ""
+	if sc.Name == "" {
+		sc.Name = string(sc.Action) + " " + sc.Cron
+	}
+	m.sched.mu.Lock()
+	m.sched.put(&sc, cs, time.Now())
+	out := sc
+	m.sched.mu.Unlock()
+	log.Info("schedule created", "id", sc.ID, "server", serverID, "action", sc.Action, "cron", sc.Cron)
+	_ = m.persistSchedules()
+	return out, nil
+}
+
+func (m *Manager) UpdateSchedule(serverID, id string, upd Schedule) (Schedule, error) {
+	cs, err := upd.validate()
+	if err != nil {
+		return Schedule{}, err
+	}
+	m.sched.mu.Lock()
+	sc, ok := m.sched.jobs[id]
+	if !ok || sc.ServerID != serverID {
+		m.sched.mu.Unlock()
+		return Schedule{}, ErrScheduleNotFound
+	}
+	upd.ID, upd.ServerID = sc.ID, sc.ServerID
+	upd.LastRun, upd.LastError = sc.LastRun, sc.LastError
+	if upd.Name == "" {
+		upd.Name = sc.Name
+	}
+	m.sched.put(&upd, cs, time.Now())
+	out := upd
+	m.sched.mu.Unlock()
+	log.Info("schedule updated", "id", id, "server", serverID, "action", upd.Action, "cron", upd.Cron)
+	_ = m.persistSchedules()
+	return out, nil
+}
+
+func (m *Manager) DeleteSchedule(serverID, id string) error {
+	m.sched.mu.Lock()
+	sc, ok := m.sched.jobs[id]
+	if !ok || sc.ServerID != serverID {
+		m.sched.mu.Unlock()
+		return ErrScheduleNotFound
+	}
+	delete(m.sched.jobs, id)
+	delete(m.sched.parsed, id)
+	m.sched.mu.Unlock()
+	log.Info("schedule deleted", "id", id, "server", serverID)
+	return m.persistSchedules()
+}
+
+// deleteServerSchedules drops every job of a removed server.
+func (m *Manager) deleteServerSchedules(serverID string) {
+	m.sched.mu.Lock()
+	for id, sc := range m.sched.jobs {
+		if sc.ServerID == serverID {
+			delete(m.sched.jobs, id)
+			delete(m.sched.parsed, id)
+		}
+	}
+	m.sched.mu.Unlock()
+	_ = m.persistSchedules()
+}
+
+// runScheduler checks for due jobs every second. Each job runs in its own
+// goroutine so a slow restart does not delay other servers; a job is never
+// run twice concurrently.
+func (m *Manager) runScheduler() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-m.quit:
+			return
+		case now = <-ticker.C:
+		}
+		var due []Schedule
+		m.sched.mu.Lock()
+		for id, sc := range m.sched.jobs {
+			if !sc.Enabled || sc.NextRun == nil || now.Before(*sc.NextRun) || m.sched.running[id] {
+				continue
+			}
+			m.sched.running[id] = true
+			ran := now
+			sc.LastRun = &ran
+			m.sched.put(sc, m.sched.parsed[id], now)
+			due = append(due, *sc)
+		}
+		m.sched.mu.Unlock()
+		for _, sc := range due {
+			go m.runSchedule(sc)
+		}
+	}
+}
+
+func (m *Manager) runSchedule(sc Schedule) {
+	log.Info("running scheduled task", "id", sc.ID, "server", sc.ServerID, "action", sc.Action)
+	err := m.execSchedule(sc)
+
+	m.sched.mu.Lock()
+	delete(m.sched.running, sc.ID)
+	if cur, ok := m.sched.jobs[sc.ID]; ok {
+		cur.LastError = ""
+		if err != nil {
+			cur.LastError = err.Error()
+		}
+	}
+	m.sched.mu.Unlock()
+	_ = m.persistSchedules()
+
+	data := map[string]any{"scheduleId": sc.ID, "name": sc.Name, "action": sc.Action}
+	if err != nil {
+		log.Error("scheduled task failed", "id", sc.ID, "server", sc.ServerID, "action", sc.Action, "err", err)
+		data["error"] = err.Error()
+		m.bus.Publish(events.Event{Type: "schedule.failed", ServerID: sc.ServerID, Data: data})
+		return
+	}
+	m.bus.Publish(events.Event{Type: "schedule.ran", ServerID: sc.ServerID, Data: data})
+}
+
+func (m *Manager) execSchedule(sc Schedule) error {
+	s, ok := m.Get(sc.ServerID)
+	if !ok {
+		return os.ErrNotExist
+	}
+	switch sc.Action {
+	case ActionStart:
+		return s.Start(m.bus)
+	case ActionStop:
+		if sc.DelaySec > 0 && s.State() == StateRunning {
+			return s.StopAfter(m.bus, StopOptions{DelaySec: sc.DelaySec, Message: sc.Message})
+		}
+		return s.Stop(m.bus)
+	case ActionRestart:
+		if sc.DelaySec > 0 && s.State() == StateRunning {
+			return s.RestartAfter(m.bus, StopOptions{DelaySec: sc.DelaySec, Message: sc.Message})
+		}
+		return s.Restart(m.bus)
+	case ActionCommand:
+		return s.SendCommand(sc.Command)
+	case ActionBackup:
+		_, err := m.CreateBackup(sc.ServerID)
+		return err
+	}
+	return fmt.Errorf("unsupported schedule action: %q", sc.Action)
+}
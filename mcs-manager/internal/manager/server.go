@@ -2,21 +2,17 @@ package manager
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"runtime"
-	"strconv"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
 
-	"obsidian/internal/query"
 	"obsidian/internal/server"
-	"obsidian/internal/util"
 	"obsidian/pkg/events"
 )
 
@@ -29,189 +25,494 @@ const (
 
 type ServerConfig = server.ServerConfig
 
-type ServerState string
-
 const (
-	StateStopped  ServerState = "stopped"
-	StateRunning  ServerState = "running"
-	StateStarting ServerState = "starting"
-	StateCrashed  ServerState = "crashed"
+	defaultStopTimeout = 60 * time.Second
+	// killGrace is how long SIGTERM gets before escalating to SIGKILL
+	killGrace = 10 * time.Second
 )
 
 type ServerInfo struct {
-	Config      server.ServerConfig `json:"config"`
-	State       ServerState         `json:"state"`
-	PID         int                 `json:"pid"`
-	UptimeSec   int64               `json:"uptimeSec"`
-	LastExitErr string              `json:"lastExitErr"`
-	Players     *PlayerInfo         `json:"players,omitempty"`
+	Config            server.ServerConfig `json:"config"`
+	State             ServerState         `json:"state"`
+	PID               int                 `json:"pid"`
+	UptimeSec         int64               `json:"uptimeSec"`
+	StartupDurationMs int64               `json:"startupDurationMs,omitempty"`
+	LastExitErr       string              `json:"lastExitErr"`
+	Players           *PlayerInfo         `json:"players,omitempty"`
+	StatusAgeMs       int64               `json:"statusAgeMs,omitempty"` // age of the cached Players snapshot
+	PendingStop       *PendingStop        `json:"pendingStop,omitempty"`
+	CommandLine       []string            `json:"commandLine,omitempty"` // of the current or last start
+	Limits            *CgroupLimits       `json:"limits,omitempty"`      // in effect for the running process
 }
 
 type PlayerInfo struct {
-	Current int `json:"current"`
-	Max     int `json:"max"`
+	Current int      `json:"current"`
+	Max     int      `json:"max"`
+	Names   []string `json:"names,omitempty"`
 }
 
+// Server is a single managed Minecraft server. All mutable fields are guarded
+// by mu; state changes go through setStateLocked.
 type Server struct {
-	cfg     ServerConfig
-	cmd     *exec.Cmd
-	stdin   io.WriteCloser
-	logf    *os.File
-	state   atomic.Value
-	startAt time.Time
-	lastErr string
-}
+	cfg    ServerConfig
+	runDir string
+
+	mu              sync.Mutex
+	state           ServerState
+	cmd             *exec.Cmd
+	pid             int
+	stdin           io.WriteCloser
+	startAt         time.Time
+	startupDuration time.Duration
+	lastErr         string
+	failReason      string // overrides the exit error when a failed start was killed
+	cmdline         []string
+	limits          *CgroupLimits
+	prepCancel      context.CancelFunc // cancels a start that has no process yet
+	prepDone        chan struct{}      // closed once that start launched or gave up
+	done            chan struct{}      // closed once the current process has exited
 
-func (s *Server) State() ServerState { return s.state.Load().(ServerState) }
+	writeMu   sync.Mutex // serializes console writes
+	waiters   consoleWaiters
+	countdown countdown
+	sup       supervisor
+	rcon      rconConn
+	status    statusCache
+	players   playerTracker
+	sessions  *sessionLog
+	profiles  *profileCache
+	runtimes  *runtimeRegistry
+	quit      <-chan struct{} // closed when the manager shuts down
+	removed   bool            // set once Delete dropped the server, guarded by mu
+	listMu    sync.Mutex      // serializes edits of the whitelist, ops and ban files
+	hookMu    sync.Mutex      // serializes hooks
+	hooks     sync.WaitGroup
+}
 
 func (s *Server) Info() ServerInfo {
+	s.mu.Lock()
+	state := s.state
 	up := int64(0)
 	if !s.startAt.IsZero() {
 		up = int64(time.Since(s.startAt).Seconds())
 	}
 	pid := 0
-	if s.cmd != nil && s.cmd.Process != nil {
-		pid = s.cmd.Process.Pid
+	if s.aliveLocked() {
+		pid = s.pid
 	}
+	lastErr, startup, cmdline, limits := s.lastErr, s.startupDuration, s.cmdline, s.limits
+	s.mu.Unlock()
 
-	// Try to read player info if server is running
 	var players *PlayerInfo
-	if s.State() == StateRunning {
-		// Try to ping the server directly on its port
-		if status, err := query.PingServer("localhost", s.cfg.Port, 2*time.Second); err == nil {
-			players = &PlayerInfo{Current: status.Players.Online, Max: status.Players.Max}
-		} else {
-			// Fallback: Try to read from logs if ping fails
-			logPath := filepath.Join(s.cfg.Path, "mcs.log")
-			if current, max, err := util.ReadPlayersFromLog(logPath); err == nil && (current > 0 || max > 0) {
-				// If max is not set, try to read from config
-				if max == 0 {
-					if configMax, cfgErr := util.ReadMaxPlayersFromConfig(filepath.Join(s.cfg.Path, "server.properties")); cfgErr == nil {
-						max = configMax
-					}
-				}
-				players = &PlayerInfo{Current: current, Max: max}
-			} else {
-				// Last resort: read max from config only
-				if max, cfgErr := util.ReadMaxPlayersFromConfig(filepath.Join(s.cfg.Path, "server.properties")); cfgErr == nil {
-					players = &PlayerInfo{Current: 0, Max: max}
-				}
-			}
+	var statusAge int64
+	if state == StateRunning {
+		if p, at := s.cachedStatus(); !at.IsZero() {
+			players, statusAge = p, time.Since(at).Milliseconds()
 		}
 	}
 
-	return ServerInfo{Config: s.cfg, State: s.State(), PID: pid, UptimeSec: up, LastExitErr: s.lastErr, Players: players}
+	return ServerInfo{
+		Config:            s.cfg,
+		State:             state,
+		PID:               pid,
+		UptimeSec:         up,
+		LastExitErr:       lastErr,
+		StartupDurationMs: startup.Milliseconds(),
+		Players:           players,
+		StatusAgeMs:       statusAge,
+		PendingStop:       s.PendingStop(),
+		CommandLine:       cmdline,
+		Limits:            limits,
+	}
 }
 
 func (s *Server) Start(bus *events.Bus) error {
-	if s.State() == StateRunning {
-		log.Debug("server already running", "id", s.cfg.ID, "name", s.cfg.Name)
-		return nil
+	s.sup.cancelPending()
+	return s.start(bus)
+}
+
+func (s *Server) start(bus *events.Bus) error {
+	// Claim the server first: probing the runtime and the pre-start hook run
+	// external commands without the lock, and Stop or Shutdown may cancel them
+	s.mu.Lock()
+	select {
+	case <-s.quit:
+		s.mu.Unlock()
+		return ErrShuttingDown
+	default:
+	}
+	if s.removed {
+		s.mu.Unlock()
+		return os.ErrNotExist
 	}
-	log.Info("starting server", "id", s.cfg.ID, "name", s.cfg.Name, "port", s.cfg.Port)
-	s.state.Store(StateStarting)
-	jar := filepath.Join(s.cfg.Path, "server.jar")
-	java := "java"
-	if runtime.GOOS == "windows" {
-		java = "java.exe"
+	if !canTransition(s.state, StateStarting) {
+		s.mu.Unlock()
+		log.Debug("cannot start server", "id", s.cfg.ID, "state", s.state)
+		return &TransitionError{ServerID: s.cfg.ID, From: s.state, To: StateStarting}
+	}
+	if s.aliveLocked() {
+		s.mu.Unlock()
+		log.Warn("previous process still alive, refusing to start", "id", s.cfg.ID)
+		return ErrStillRunning
+	}
+	from := s.state
+	_ = s.setStateLocked(bus, StateStarting)
+	ctx, cancel := context.WithCancel(context.Background())
+	prepDone := make(chan struct{})
+	s.prepCancel, s.prepDone = cancel, prepDone
+	s.mu.Unlock()
+
+	var java string
+	launchErr := validateLaunch(s.cfg)
+	if launchErr == nil {
+		java, launchErr = s.selectJava()
 	}
-	args := []string{"-Xmx" + strconv.Itoa(s.cfg.MemoryMB) + "M", "-jar", jar, "nogui"}
-	cmd := exec.Command(java, args...)
+	if launchErr == nil && s.cfg.Hooks != nil {
+		launchErr = s.runHook(ctx, bus, "pre-start", s.cfg.Hooks.PreStart)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prepCancel, s.prepDone = nil, nil
+	defer close(prepDone)
+	defer cancel()
+	select {
+	case <-s.quit:
+		launchErr = ErrShuttingDown
+	default:
+		if ctx.Err() != nil {
+			launchErr = errors.New("start cancelled")
+		}
+	}
+	if launchErr != nil {
+		log.Error("refusing to start server", "id", s.cfg.ID, "err", launchErr)
+		s.lastErr = launchErr.Error()
+		// Back to where we came from, or stopped if Stop cancelled the start
+		if s.state == StateStopping {
+			from = StateStopped
+		}
+		_ = s.setStateLocked(bus, from)
+		return launchErr
+	}
+	log.Info("starting server", "id", s.cfg.ID, "name", s.cfg.Name, "port", s.cfg.Port, "java", java)
+	s.failReason, s.startupDuration = "", 0
+	jar := s.jarPath()
+	cmd := exec.Command(java, s.launchArgs(jar)...)
+	s.cmdline = cmd.Args
 	cmd.Dir = s.cfg.Path
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-	stdin, _ := cmd.StdinPipe()
-	logFile, _ := os.OpenFile(filepath.Join(s.cfg.Path, "mcs.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	s.stdin, s.cmd, s.logf = stdin, cmd, logFile
+	cmd.Env = s.environ()
+	setProcessGroup(cmd)
+
+	// The process writes straight into mcs.log and reads commands from a named
+	// pipe, so it keeps running and stays controllable across manager restarts.
+	logFile, err := os.OpenFile(s.logPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Error("failed to open server log", "id", s.cfg.ID, "err", err)
+		s.lastErr = err.Error()
+		_ = s.setStateLocked(bus, StateCrashed)
+		return err
+	}
+	defer logFile.Close()
+	offset, _ := logFile.Seek(0, io.SeekEnd)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	var stdin io.WriteCloser
+	if fifo, err := openConsoleFIFO(s.consolePath()); err == nil {
+		cmd.Stdin, stdin = fifo, fifo
+	} else {
+		log.Debug("console pipe unavailable, using stdin pipe", "id", s.cfg.ID, "err", err)
+		stdin, _ = cmd.StdinPipe()
+	}
+	limits, cgroup := s.prepareLimits()
+	if cgroup != nil {
+		defer cgroup.Close()
+	}
+	startInCgroup(cmd, cgroup)
 	if err := cmd.Start(); err != nil {
 		log.Error("failed to start server", "id", s.cfg.ID, "err", err)
-		s.state.Store(StateCrashed)
+		s.releaseCgroup(limits)
+		if stdin != nil {
+			_ = stdin.Close()
+		}
+		s.lastErr = err.Error()
+		_ = s.setStateLocked(bus, StateCrashed)
 		return err
 	}
 	log.Debug("server process started", "id", s.cfg.ID, "pid", cmd.Process.Pid)
-	s.state.Store(StateRunning)
+	done := make(chan struct{})
+	s.cmd, s.stdin, s.pid, s.done = cmd, stdin, cmd.Process.Pid, done
 	s.startAt = time.Now()
+	s.limits = limits
+	s.saveRuntime(runtimeState{PID: s.pid, StartedAt: s.startAt, Jar: jar, Args: s.cmdline})
 	log.Info("server started successfully", "id", s.cfg.ID, "name", s.cfg.Name, "pid", cmd.Process.Pid)
 	bus.Publish(events.Event{Type: "server.started", ServerID: s.cfg.ID})
 
-	go s.pipe(bus, stdout, "stdout")
-	go s.pipe(bus, stderr, "stderr")
+	go s.follow(bus, offset, done)
+	go s.awaitReady(bus, done)
 	go func() {
 		err := cmd.Wait()
-		_ = logFile.Close()
-		if err != nil {
-			s.lastErr = err.Error()
-			s.state.Store(StateCrashed)
-			log.Error("server crashed", "id", s.cfg.ID, "err", err)
-		} else {
-			s.state.Store(StateStopped)
-			log.Info("server stopped", "id", s.cfg.ID)
-		}
-		bus.Publish(events.Event{Type: "server.exited", ServerID: s.cfg.ID})
+		s.exited(bus, err, done)
 	}()
 	return nil
 }
 
+// exited tears down console plumbing once the process is gone and hands the
+// exit over to the restart supervisor. An exit while stopping counts as
+// requested and always ends in StateStopped.
+func (s *Server) exited(bus *events.Bus, err error, done chan struct{}) {
+	s.mu.Lock()
+	requested := s.state == StateStopping
+	stdin, limits := s.stdin, s.limits
+	s.stdin, s.limits = nil, nil
+	uptime := time.Since(s.startAt)
+	if s.failReason != "" {
+		err = errors.New(s.failReason)
+	}
+	to := StateStopped
+	if err != nil {
+		s.lastErr = err.Error()
+		if !requested {
+			to = StateCrashed
+		}
+	}
+	if terr := s.setStateLocked(bus, to); terr != nil {
+		log.Error("unexpected state on exit", "id", s.cfg.ID, "err", terr)
+		s.state = to
+	}
+	postStop := ""
+	if s.cfg.Hooks != nil {
+		postStop = s.cfg.Hooks.PostStop
+	}
+	if postStop != "" {
+		s.hooks.Add(1)
+		defer s.hooks.Done()
+	}
+	close(done)
+	s.mu.Unlock()
+
+	if stdin != nil {
+		_ = stdin.Close()
+	}
+	s.clearRuntime()
+	s.releaseCgroup(limits)
+	s.clearStatus()
+	s.resetPlayers(bus)
+	s.closeRcon()
+	s.releaseWaiters()
+	s.clearCountdown()
+	if to == StateCrashed {
+		log.Error("server crashed", "id", s.cfg.ID, "err", err)
+	} else {
+		log.Info("server stopped", "id", s.cfg.ID)
+	}
+	bus.Publish(events.Event{Type: "server.exited", ServerID: s.cfg.ID})
+	if herr := s.runHook(context.Background(), bus, "post-stop", postStop, "MCS_EXIT_STATE="+string(to)); herr != nil {
+		s.mu.Lock()
+		s.lastErr = herr.Error()
+		if err != nil {
+			s.lastErr = err.Error() + "; " + herr.Error()
+		}
+		s.mu.Unlock()
+	}
+	s.handleExit(bus, err, requested, uptime)
+}
+
+// maxConsoleLine bounds a single console line, longer lines are truncated.
+const maxConsoleLine = 1 << 20
+
 func (s *Server) pipe(bus *events.Bus, r io.Reader, stream string) {
-	scanner := bufio.NewScanner(r)
-	// Use smaller buffer for more responsive logging (default is 65536)
-	scanner.Buffer(make([]byte, 4096), 4096)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if s.logf != nil {
-			_, _ = s.logf.WriteString(line + "\n")
+	br := bufio.NewReaderSize(r, 64*1024)
+	var buf []byte
+	for {
+		frag, more, err := br.ReadLine()
+		if err != nil {
+			return
+		}
+		// A stack trace or plugin dump may be longer than the reader's buffer,
+		// keep reading instead of giving up on the rest of the console
+		if len(buf) < maxConsoleLine {
+			buf = append(buf, frag[:min(len(frag), maxConsoleLine-len(buf))]...)
 		}
+		if more {
+			continue
+		}
+		line := string(buf)
+		buf = buf[:0]
+		s.checkReadyLine(bus, line)
+		s.trackPlayers(bus, line)
+		s.notifyWaiters(line)
 		bus.Publish(events.Event{Type: "server.log", ServerID: s.cfg.ID, Data: map[string]any{"stream": stream, "line": line}})
 	}
 }
 
 func (s *Server) SendCommand(cmd string) error {
-	if s.stdin == nil {
+	s.mu.Lock()
+	stdin := s.stdin
+	s.mu.Unlock()
+	if stdin == nil {
 		log.Warn("server not running, cannot send command", "id", s.cfg.ID, "cmd", cmd)
-		return errors.New("not running")
+		return ErrNotRunning
 	}
 	log.Debug("sending command to server", "id", s.cfg.ID, "cmd", cmd)
-	_, err := io.WriteString(s.stdin, cmd+"\n")
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := io.WriteString(stdin, cmd+"\n")
 	return err
 }
 
-func (s *Server) Stop(bus *events.Bus) {
-	if s.State() != StateRunning {
-		log.Debug("server not running, cannot stop", "id", s.cfg.ID)
-		return
+// Stop asks the server to shut down via the "stop" console command. If the
+// process is still alive after the stop timeout it receives SIGTERM and, after
+// a short grace period, SIGKILL for its whole process group.
+func (s *Server) Stop(bus *events.Bus) error {
+	s.sup.cancelPending()
+	s.mu.Lock()
+	if err := s.setStateLocked(bus, StateStopping); err != nil {
+		s.mu.Unlock()
+		log.Debug("cannot stop server", "id", s.cfg.ID, "err", err)
+		return err
+	}
+	if s.prepCancel != nil {
+		s.prepCancel()
+		s.mu.Unlock()
+		log.Info("cancelling server start", "id", s.cfg.ID, "name", s.cfg.Name)
+		return nil
 	}
+	pid, done := s.pid, s.done
+	s.mu.Unlock()
+
 	log.Info("stopping server", "id", s.cfg.ID, "name", s.cfg.Name)
-	if s.stdin != nil {
-		_, _ = io.WriteString(s.stdin, "stop\n")
+	if err := s.SendCommand("stop"); err != nil {
+		log.Warn("failed to send stop command, terminating", "id", s.cfg.ID, "err", err)
+		_ = terminateProcess(pid)
+	}
+	go s.escalateStop(bus, pid, done)
+	return nil
+}
+
+func (s *Server) escalateStop(bus *events.Bus, pid int, done chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-time.After(s.stopTimeout()):
+	}
+	log.Warn("server did not stop in time, sending SIGTERM", "id", s.cfg.ID, "pid", pid)
+	bus.Publish(events.Event{Type: "server.stop_escalated", ServerID: s.cfg.ID, Data: map[string]any{"signal": "SIGTERM"}})
+	if err := terminateProcess(pid); err != nil {
+		log.Error("failed to terminate server", "id", s.cfg.ID, "pid", pid, "err", err)
+	}
+	select {
+	case <-done:
+		return
+	case <-time.After(killGrace):
+	}
+	log.Warn("server ignored SIGTERM, sending SIGKILL", "id", s.cfg.ID, "pid", pid)
+	bus.Publish(events.Event{Type: "server.stop_escalated", ServerID: s.cfg.ID, Data: map[string]any{"signal": "SIGKILL"}})
+	if err := killProcess(pid); err != nil {
+		log.Error("failed to kill server", "id", s.cfg.ID, "pid", pid, "err", err)
+	}
+}
+
+// Kill immediately sends SIGKILL to the server's process group.
+func (s *Server) Kill(bus *events.Bus) error {
+	s.sup.cancelPending()
+	s.mu.Lock()
+	if s.prepCancel != nil {
+		s.prepCancel()
+		_ = s.setStateLocked(bus, StateStopping)
+		s.mu.Unlock()
+		log.Warn("cancelling server start", "id", s.cfg.ID)
+		return nil
+	}
+	if !s.aliveLocked() {
+		s.mu.Unlock()
+		return ErrNotRunning
+	}
+	if s.state != StateStopping {
+		if err := s.setStateLocked(bus, StateStopping); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	pid := s.pid
+	s.mu.Unlock()
+	log.Warn("killing server", "id", s.cfg.ID, "pid", pid)
+	return killProcess(pid)
+}
+
+func (s *Server) stopTimeout() time.Duration {
+	if s.cfg.StopTimeoutSec > 0 {
+		return time.Duration(s.cfg.StopTimeoutSec) * time.Second
 	}
+	return defaultStopTimeout
+}
+
+// alive reports whether the server's process (started or re-adopted) is still around.
+func (s *Server) alive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.aliveLocked()
+}
+
+func (s *Server) aliveLocked() bool {
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// waitExit blocks until the current process has exited or the timeout expires.
+func (s *Server) waitExit(timeout time.Duration) error {
+	s.mu.Lock()
+	done := s.done
+	if s.prepDone != nil {
+		done = s.prepDone
+	}
+	s.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return ErrStillRunning
+	}
+}
+
+// cancelStart cancels a start that has not launched its process yet and
+// returns a channel closed once it gave up, or nil if there is none.
+func (s *Server) cancelStart() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.prepCancel == nil {
+		return nil
+	}
+	s.prepCancel()
+	return s.prepDone
 }
 
 func (s *Server) Restart(bus *events.Bus) error {
 	log.Info("restarting server", "id", s.cfg.ID, "name", s.cfg.Name)
-	
-	// If not running, just start it
-	if s.State() != StateRunning {
+
+	switch s.State() {
+	case StateStopped, StateCrashed:
+		// If not running, just start it
 		return s.Start(bus)
-	}
-	
-	s.Stop(bus)
-	
-	// Wait for server to actually stop by listening to exit event
-	sub := bus.Subscribe()
-	defer bus.Unsubscribe(sub)
-	
-	// Wait with timeout (max 30 seconds)
-	timeout := time.After(30 * time.Second)
-	for {
-		select {
-		case e := <-sub.Ch:
-			if e.Type == "server.exited" && e.ServerID == s.cfg.ID {
-				log.Debug("server stopped successfully, starting again", "id", s.cfg.ID)
-				return s.Start(bus)
-			}
-		case <-timeout:
-			log.Warn("timeout waiting for server to stop, forcing start anyway", "id", s.cfg.ID)
-			return s.Start(bus)
+	case StateStarting, StateRunning:
+		if err := s.Stop(bus); err != nil {
+			return err
 		}
 	}
+
+	// Stop escalates to SIGKILL on its own, so the old process is gone by then
+	if err := s.waitExit(s.stopTimeout() + 2*killGrace); err != nil {
+		log.Error("server did not exit, not starting a second process", "id", s.cfg.ID)
+		return err
+	}
+	log.Debug("server stopped successfully, starting again", "id", s.cfg.ID)
+	return s.Start(bus)
 }
@@ -0,0 +1,314 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Session is one visit of a player, appended to <root>/sessions/<serverId>.jsonl
+// when the player leaves or the server stops.
+type Session struct {
+	Player   string    `json:"player"`
+	UUID     string    `json:"uuid,omitempty"`
+	ServerID string    `json:"serverId"`
+	JoinedAt time.Time `json:"joinedAt"`
+	LeftAt   time.Time `json:"leftAt"`
+	Reason   string    `json:"reason,omitempty"` // empty while still online
+}
+
+type SessionFilter struct {
+	Player string // name or UUID, case-insensitive
+	Since  time.Time
+	Until  time.Time
+	Limit  int // Sessions only; 0 returns all
+}
+
+type PlayerPlaytime struct {
+	Player      string    `json:"player"`
+	UUID        string    `json:"uuid,omitempty"`
+	Sessions    int       `json:"sessions"`
+	PlaytimeSec int64     `json:"playtimeSec"`
+	LastSeen    time.Time `json:"lastSeen"`
+	Online      bool      `json:"online"`
+}
+
+type DailyStats struct {
+	Date          string `json:"date"` // YYYY-MM-DD in the manager's time zone
+	UniquePlayers int    `json:"uniquePlayers"`
+	Sessions      int    `json:"sessions"`
+	Peak          int    `json:"peak"`
+}
+
+type PeakStats struct {
+	Players int        `json:"players"`
+	At      *time.Time `json:"at,omitempty"`
+}
+
+type PlayerStats struct {
+	Since    time.Time        `json:"since"`
+	Until    time.Time        `json:"until"`
+	Playtime []PlayerPlaytime `json:"playtime"`
+	Daily    []DailyStats     `json:"daily"`
+	Peak     PeakStats        `json:"peak"`
+}
+
+// sessionLog serializes appends to the per-server session files.
+type sessionLog struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func (l *sessionLog) file(serverID string) string {
+	return filepath.Join(l.dir, serverID+".jsonl")
+}
+
+func (l *sessionLog) record(sess Session) {
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		log.Error("failed to create sessions directory", "err", err)
+		return
+	}
+	f, err := os.OpenFile(l.file(sess.ServerID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Error("failed to open session log", "server", sess.ServerID, "err", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		log.Error("failed to write session", "server", sess.ServerID, "err", err)
+	}
+}
+
+// read returns all sessions of a server overlapping the filter window, oldest first.
+func (l *sessionLog) read(serverID string, flt SessionFilter) ([]Session, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.file(serverID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Session{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	out := []Session{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var sess Session
+		if json.Unmarshal(sc.Bytes(), &sess) != nil || !flt.match(sess) {
+			continue
+		}
+		out = append(out, sess)
+	}
+	return out, sc.Err()
+}
+
+func (flt SessionFilter) match(s Session) bool {
+	if flt.Player != "" && !strings.EqualFold(s.Player, flt.Player) && !strings.EqualFold(s.UUID, flt.Player) {
+		return false
+	}
+	if !flt.Since.IsZero() && s.LeftAt.Before(flt.Since) {
+		return false
+	}
+	if !flt.Until.IsZero() && s.JoinedAt.After(flt.Until) {
+		return false
+	}
+	return true
+}
+
+// Sessions returns completed and ongoing sessions of a server, newest first.
+func (m *Manager) Sessions(serverID string, flt SessionFilter) ([]Session, error) {
+	s, ok := m.Get(serverID)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	list, err := m.sessions.read(serverID, flt)
+	if err != nil {
+		return nil, err
+	}
+	list = append(list, s.openSessions(flt)...)
+	sort.Slice(list, func(i, j int) bool { return list[i].JoinedAt.After(list[j].JoinedAt) })
+	if flt.Limit > 0 && len(list) > flt.Limit {
+		list = list[:flt.Limit]
+	}
+	return list, nil
+}
+
+// openSessions are the sessions of players still online, ending now.
+func (s *Server) openSessions(flt SessionFilter) []Session {
+	now := time.Now()
+	var out []Session
+	for _, p := range s.OnlinePlayers() {
+		sess := Session{Player: p.Name, UUID: p.UUID, ServerID: s.cfg.ID, JoinedAt: p.JoinedAt, LeftAt: now}
+		if flt.match(sess) {
+			out = append(out, sess)
+		}
+	}
+	return out
+}
+
+// maxStatsWindow bounds PlayerStats, which builds one entry per day.
+const maxStatsWindow = 366 * 24 * time.Hour
+
+var ErrStatsWindow = errors.New("stats window must be at most 366 days")
+
+// PlayerStats aggregates playtime per player, unique players and peak
+// concurrency per day, and the overall peak between since and until.
+func (m *Manager) PlayerStats(serverID string, flt SessionFilter) (PlayerStats, error) {
+	if flt.Until.IsZero() {
+		flt.Until = time.Now()
+	}
+	if flt.Since.IsZero() {
+		flt.Since = flt.Until.AddDate(0, 0, -7)
+	}
+	if flt.Until.Sub(flt.Since) > maxStatsWindow {
+		return PlayerStats{}, ErrStatsWindow
+	}
+	flt.Limit = 0
+	list, err := m.Sessions(serverID, flt)
+	if err != nil {
+		return PlayerStats{}, err
+	}
+	online := map[string]bool{}
+	if s, ok := m.Get(serverID); ok {
+		for _, p := range s.OnlinePlayers() {
+			online[p.Name] = true
+		}
+	}
+	// Clip sessions to the window so totals only count time inside it
+	for i := range list {
+		if list[i].JoinedAt.Before(flt.Since) {
+			list[i].JoinedAt = flt.Since
+		}
+		if list[i].LeftAt.After(flt.Until) {
+			list[i].LeftAt = flt.Until
+		}
+	}
+
+	st := PlayerStats{Since: flt.Since, Until: flt.Until, Playtime: []PlayerPlaytime{}, Daily: []DailyStats{}}
+	byPlayer := map[string]*PlayerPlaytime{}
+	played := map[*PlayerPlaytime]time.Duration{}
+	for _, sess := range list {
+		key := sess.UUID
+		if key == "" {
+			key = strings.ToLower(sess.Player)
+		}
+		pt, ok := byPlayer[key]
+		if !ok {
+			pt = &PlayerPlaytime{Player: sess.Player, UUID: sess.UUID}
+			byPlayer[key] = pt
+		}
+		pt.Sessions++
+		played[pt] += sess.LeftAt.Sub(sess.JoinedAt)
+		if sess.LeftAt.After(pt.LastSeen) {
+			pt.LastSeen = sess.LeftAt
+		}
+		pt.Online = pt.Online || online[sess.Player]
+	}
+	for _, pt := range byPlayer {
+		pt.PlaytimeSec = int64(played[pt].Seconds())
+		st.Playtime = append(st.Playtime, *pt)
+	}
+	sort.Slice(st.Playtime, func(i, j int) bool { return st.Playtime[i].PlaytimeSec > st.Playtime[j].PlaytimeSec })
+
+	edges := sessionEdges(list)
+	if peak, at := peakConcurrency(edges); peak > 0 {
+		st.Peak = PeakStats{Players: peak, At: &at}
+	}
+	st.Daily = dailyStats(list, edges, flt.Since, flt.Until)
+	return st, nil
+}
+
+type sessionEdge struct {
+	at    time.Time
+	delta int
+}
+
+// sessionEdges returns the joins and leaves of list in order. Leaves sort
+// before joins at the same instant so a reconnect is not counted twice.
+func sessionEdges(list []Session) []sessionEdge {
+	edges := make([]sessionEdge, 0, 2*len(list))
+	for _, s := range list {
+		edges = append(edges, sessionEdge{s.JoinedAt, 1}, sessionEdge{s.LeftAt, -1})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+	return edges
+}
+
+// peakConcurrency returns the highest number of simultaneous sessions and
+// when it was first reached.
+func peakConcurrency(edges []sessionEdge) (int, time.Time) {
+	cur, peak := 0, 0
+	var at time.Time
+	for _, e := range edges {
+		cur += e.delta
+		if cur > peak {
+			peak, at = cur, e.at
+		}
+	}
+	return peak, at
+}
+
+// dailyStats buckets the sessions into days of the manager's time zone. The
+// peaks come from a single sweep over edges.
+func dailyStats(list []Session, edges []sessionEdge, since, until time.Time) []DailyStats {
+	since, until = since.Local(), until.Local()
+	// Start of every day, followed by the end of the last one
+	var days []time.Time
+	for day := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.Local); day.Before(until); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return []DailyStats{}
+	}
+	days = append(days, days[len(days)-1].AddDate(0, 0, 1))
+
+	out := make([]DailyStats, len(days)-1)
+	players := make([]map[string]bool, len(out))
+	for i := range out {
+		out[i].Date = days[i].Format("2006-01-02")
+		players[i] = map[string]bool{}
+	}
+	for _, sess := range list {
+		first := sort.Search(len(out), func(i int) bool { return sess.JoinedAt.Before(days[i+1]) })
+		for i := first; i < len(out) && sess.LeftAt.After(days[i]); i++ {
+			out[i].Sessions++
+			players[i][strings.ToLower(sess.Player)] = true
+		}
+	}
+
+	cur, e := 0, 0
+	for i := range out {
+		// Players online when the day starts
+		for ; e < len(edges) && !edges[e].at.After(days[i]); e++ {
+			cur += edges[e].delta
+		}
+		peak := cur
+		for ; e < len(edges) && edges[e].at.Before(days[i+1]); e++ {
+			cur += edges[e].delta
+			peak = max(peak, cur)
+		}
+		out[i].Peak = peak
+		out[i].UniquePlayers = len(players[i])
+	}
+	return out
+}
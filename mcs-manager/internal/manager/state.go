@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+
+	"obsidian/pkg/events"
+)
+
+type ServerState string
+
+const (
+	StateStopped  ServerState = "stopped"
+	StateStarting ServerState = "starting"
+	StateRunning  ServerState = "running"
+	StateStopping ServerState = "stopping"
+	StateCrashed  ServerState = "crashed"
+)
+
+// transitions lists the legal state changes of a server:
+//
+//	stopped/crashed -> starting -> running -> stopping -> stopped
+//
+// A process may also exit on its own while starting or running.
+var transitions = map[ServerState][]ServerState{
+	StateStopped:  {StateStarting},
+	StateCrashed:  {StateStarting},
+	StateStarting: {StateRunning, StateStopping, StateStopped, StateCrashed},
+	StateRunning:  {StateStopping, StateStopped, StateCrashed},
+	StateStopping: {StateStopped},
+}
+
+// TransitionError is returned when an operation is not allowed in the server's current state.
+type TransitionError struct {
+	ServerID string
+	From, To ServerState
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("server %s: cannot go from %s to %s", e.ServerID, e.From, e.To)
+}
+
+var (
+	ErrNotRunning   = errors.New("not running")
+	ErrStillRunning = errors.New("previous server process is still running")
+	ErrShuttingDown = errors.New("manager is shutting down")
+)
+
+func canTransition(from, to ServerState) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// setStateLocked moves the server to a new state and publishes server.state_changed.
+// s.mu must be held.
+func (s *Server) setStateLocked(bus *events.Bus, to ServerState) error {
+	from := s.state
+	if !canTransition(from, to) {
+		return &TransitionError{ServerID: s.cfg.ID, From: from, To: to}
+	}
+	s.state = to
+	if bus != nil {
+		bus.Publish(events.Event{Type: "server.state_changed", ServerID: s.cfg.ID, Data: map[string]any{
+			"from": from,
+			"to":   to,
+		}})
+	}
+	return nil
+}
+
+func (s *Server) State() ServerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// isActive reports whether the server has a live process (starting, running or stopping).
+func (st ServerState) isActive() bool {
+	return st == StateStarting || st == StateRunning || st == StateStopping
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"obsidian/internal/query"
+	"obsidian/internal/util"
+	"obsidian/pkg/events"
+)
+
+// statusInterval is how often a running server is probed for player info.
+const statusInterval = 5 * time.Second
+
+var errQueryDisabled = errors.New("query is not enabled")
+
+// statusCache holds the latest probe result so Info never blocks on the network.
+type statusCache struct {
+	mu        sync.Mutex
+	players   *PlayerInfo
+	checkedAt time.Time
+}
+
+func (s *Server) cachedStatus() (*PlayerInfo, time.Time) {
+	s.status.mu.Lock()
+	defer s.status.mu.Unlock()
+	return s.status.players, s.status.checkedAt
+}
+
+func (s *Server) clearStatus() {
+	s.status.mu.Lock()
+	defer s.status.mu.Unlock()
+	s.status.players, s.status.checkedAt = nil, time.Time{}
+}
+
+// pollStatus probes a running server until its process exits and broadcasts
+// each result as a server.info event.
+func (s *Server) pollStatus(bus *events.Bus, done <-chan struct{}) {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+	for {
+		if s.State() == StateRunning {
+			players := s.probePlayers()
+			s.status.mu.Lock()
+			s.status.players, s.status.checkedAt = players, time.Now()
+			s.status.mu.Unlock()
+			bus.Publish(events.Event{Type: "server.info", ServerID: s.cfg.ID, Data: s.Info()})
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) probePlayers() *PlayerInfo {
+	// The full-stat query also knows the player names, the ping only counts
+	if stat, err := s.queryStat(); err == nil {
+		return &PlayerInfo{Current: stat.NumPlayers, Max: stat.MaxPlayers, Names: stat.Players}
+	}
+	if status, err := query.PingServer("localhost", s.cfg.Port, 2*time.Second); err == nil {
+		return &PlayerInfo{Current: status.Players.Online, Max: status.Players.Max}
+	}
+	// Fallback: players seen joining on the console, max from server.properties
+	info := &PlayerInfo{}
+	info.Max, _ = util.ReadMaxPlayersFromConfig(filepath.Join(s.cfg.Path, "server.properties"))
+	for _, p := range s.OnlinePlayers() {
+		info.Names = append(info.Names, p.Name)
+	}
+	info.Current = len(info.Names)
+	return info
+}
+
+// queryStat runs a full-stat query if enable-query is set in server.properties.
+func (s *Server) queryStat() (*query.FullStat, error) {
+	props, err := util.ParseProperties(util.GetServerPropertiesPath(s.cfg.Path))
+	if err != nil {
+		return nil, err
+	}
+	if props["enable-query"] != "true" {
+		return nil, errQueryDisabled
+	}
+	port := s.cfg.Port
+	if p, err := strconv.Atoi(props["query.port"]); err == nil && p > 0 {
+		port = p
+	}
+	return query.QueryFullStat("127.0.0.1", port, time.Second)
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/server"
+	"obsidian/pkg/events"
+)
+
+const (
+	defaultBackoffSec         = 5
+	defaultMaxBackoffSec      = 300
+	defaultCrashLoopCount     = 5
+	defaultCrashLoopWindowMin = 10
+)
+
+// supervisor restarts a server after unexpected exits according to its RestartPolicy.
+type supervisor struct {
+	mu      sync.Mutex
+	crashes []time.Time
+	retries int
+	timer   *time.Timer
+}
+
+func effectivePolicy(p *server.RestartPolicy) server.RestartPolicy {
+	if p == nil {
+		return server.RestartPolicy{Mode: server.RestartNever}
+	}
+	out := *p
+	if out.Mode == "" {
+		out.Mode = server.RestartNever
+	}
+	if out.BackoffSec <= 0 {
+		out.BackoffSec = defaultBackoffSec
+	}
+	if out.MaxBackoffSec <= 0 {
+		out.MaxBackoffSec = defaultMaxBackoffSec
+	}
+	if out.CrashLoopCount <= 0 {
+		out.CrashLoopCount = defaultCrashLoopCount
+	}
+	if out.CrashLoopWindowMin <= 0 {
+		out.CrashLoopWindowMin = defaultCrashLoopWindowMin
+	}
+	return out
+}
+
+// validateRestartPolicy rejects modes the supervisor does not know, which
+// would otherwise silently behave like "never".
+func validateRestartPolicy(p *server.RestartPolicy) error {
+	if p == nil {
+		return nil
+	}
+	switch p.Mode {
+	case "", server.RestartNever, server.RestartOnFailure, server.RestartAlways:
+		return nil
+	}
+	return fmt.Errorf("unknown restart mode %q, available: %s, %s, %s", p.Mode, server.RestartNever, server.RestartOnFailure, server.RestartAlways)
+}
+
+// cancelPending drops a scheduled restart, e.g. when the user starts or stops the server.
+func (sv *supervisor) cancelPending() {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	if sv.timer != nil {
+		sv.timer.Stop()
+		sv.timer = nil
+	}
+}
+
+// handleExit is called once the server process is gone. requested reports whether
+// the exit was initiated through Stop.
+func (s *Server) handleExit(bus *events.Bus, exitErr error, requested bool, uptime time.Duration) {
+	pol := effectivePolicy(s.cfg.RestartPolicy)
+	sv := &s.sup
+	window := time.Duration(pol.CrashLoopWindowMin) * time.Minute
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	// A run that outlived the crash-loop window counts as healthy again
+	if uptime >= window {
+		sv.retries = 0
+	}
+	if requested {
+		sv.retries = 0
+		sv.crashes = nil
+		return
+	}
+	switch pol.Mode {
+	case server.RestartAlways:
+	case server.RestartOnFailure:
+		if exitErr == nil {
+			return
+		}
+	default:
+		return
+	}
+
+	now := time.Now()
+	kept := sv.crashes[:0]
+	for _, t := range sv.crashes {
+		if now.Sub(t) < window {
+			kept = append(kept, t)
+		}
+	}
+	sv.crashes = append(kept, now)
+
+	if len(sv.crashes) >= pol.CrashLoopCount || (pol.MaxRetries > 0 && sv.retries >= pol.MaxRetries) {
+		log.Error("server is crash looping, giving up on automatic restarts", "id", s.cfg.ID, "crashes", len(sv.crashes), "retries", sv.retries)
+		bus.Publish(events.Event{Type: "server.crashloop", ServerID: s.cfg.ID, Data: map[string]any{
+			"crashes":   len(sv.crashes),
+			"windowMin": pol.CrashLoopWindowMin,
+			"retries":   sv.retries,
+		}})
+		sv.crashes = nil
+		sv.retries = 0
+		return
+	}
+
+	delay := time.Duration(pol.BackoffSec) * time.Second << uint(sv.retries)
+	if max := time.Duration(pol.MaxBackoffSec) * time.Second; delay > max || delay <= 0 {
+		delay = max
+	}
+	sv.retries++
+	attempt := sv.retries
+	log.Warn("scheduling automatic restart", "id", s.cfg.ID, "attempt", attempt, "delay", delay)
+	bus.Publish(events.Event{Type: "server.restart_scheduled", ServerID: s.cfg.ID, Data: map[string]any{
+		"attempt":  attempt,
+		"delaySec": int(delay.Seconds()),
+	}})
+	if sv.timer != nil {
+		sv.timer.Stop()
+	}
+	sv.timer = time.AfterFunc(delay, func() {
+		sv.mu.Lock()
+		sv.timer = nil
+		sv.mu.Unlock()
+		log.Info("automatically restarting server", "id", s.cfg.ID, "attempt", attempt)
+		if err := s.start(bus); err != nil {
+			// Deleted, shutting down or already started by someone else: nothing crashed
+			var te *TransitionError
+			if errors.As(err, &te) || errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrStillRunning) || errors.Is(err, ErrShuttingDown) {
+				log.Info("automatic restart abandoned", "id", s.cfg.ID, "err", err)
+				return
+			}
+			log.Error("automatic restart failed", "id", s.cfg.ID, "err", err)
+			s.handleExit(bus, err, false, 0)
+		}
+	})
+}
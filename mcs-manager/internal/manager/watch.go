@@ -0,0 +1,227 @@
+package manager
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"obsidian/internal/query"
+	"obsidian/internal/util"
+	"obsidian/pkg/events"
+)
+
+const (
+	watchInterval = 30 * time.Second
+	watchTimeout  = 5 * time.Second
+)
+
+// WatchedServer is an external server on another host that is only monitored.
+type WatchedServer struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Host string `json:"host"`
+	// Port 0 resolves the _minecraft._tcp SRV record of Host
+	Port int `json:"port"`
+}
+
+// WatchStatus is the result of the latest status ping of a watched server.
+type WatchStatus struct {
+	Online     bool        `json:"online"`
+	LatencyMs  int64       `json:"latencyMs"`
+	Version    string      `json:"version,omitempty"`
+	Protocol   int         `json:"protocol,omitempty"`
+	MOTD       string      `json:"motd,omitempty"`
+	Players    *PlayerInfo `json:"players,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CheckedAt  time.Time   `json:"checkedAt"`
+	LastOnline *time.Time  `json:"lastOnline,omitempty"`
+}
+
+type WatchInfo struct {
+	WatchedServer
+	Status *WatchStatus `json:"status"`
+}
+
+var ErrWatchNotFound = errors.New("watched server not found")
+
+type watcher struct {
+	mu     sync.Mutex
+	items  map[string]*WatchedServer
+	status map[string]*WatchStatus
+}
+
+func newWatcher() *watcher {
+	return &watcher{items: map[string]*WatchedServer{}, status: map[string]*WatchStatus{}}
+}
+
+func (w *WatchedServer) validate() error {
+	w.Host = strings.TrimSpace(w.Host)
+	if w.Host == "" {
+		return errors.New("host required")
+	}
+	if w.Port < 0 || w.Port > 65535 {
+		return errors.New("invalid port")
+	}
+	if w.Name == "" {
+		w.Name = w.Host
+	}
+	return nil
+}
+
+func (m *Manager) loadWatches() {
+	list, err := m.store.LoadWatches()
+	if err != nil {
+		log.Warn("failed to load watched servers", "err", err)
+		return
+	}
+	m.watch.mu.Lock()
+	defer m.watch.mu.Unlock()
+	for i := range list {
+		w := list[i]
+		m.watch.items[w.ID] = &w
+	}
+	log.Info("loaded watched servers", "count", len(list))
+}
+
+func (m *Manager) persistWatches() error {
+	m.watch.mu.Lock()
+	arr := make([]WatchedServer, 0, len(m.watch.items))
+	for _, w := range m.watch.items {
+		arr = append(arr, *w)
+	}
+	m.watch.mu.Unlock()
+	sort.Slice(arr, func(i, j int) bool { return arr[i].ID < arr[j].ID })
+	return m.store.SaveWatches(arr)
+}
+
+func (m *Manager) ListWatches() []WatchInfo {
+	m.watch.mu.Lock()
+	defer m.watch.mu.Unlock()
+	out := make([]WatchInfo, 0, len(m.watch.items))
+	for id, w := range m.watch.items {
+		out = append(out, WatchInfo{WatchedServer: *w, Status: m.watch.status[id]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (m *Manager) GetWatch(id string) (WatchInfo, error) {
+	m.watch.mu.Lock()
+	defer m.watch.mu.Unlock()
+	w, ok := m.watch.items[id]
+	if !ok {
+		return WatchInfo{}, ErrWatchNotFound
+	}
+	return WatchInfo{WatchedServer: *w, Status: m.watch.status[id]}, nil
+}
+
+func (m *Manager) CreateWatch(w WatchedServer) (WatchInfo, error) {
+	if err := w.validate(); err != nil {
+		return WatchInfo{}, err
+	}
+	w.ID = util.RandID()
+	m.watch.mu.Lock()
+	m.watch.items[w.ID] = &w
+	m.watch.mu.Unlock()
+	if err := m.persistWatches(); err != nil {
+		return WatchInfo{}, err
+	}
+	log.Info("watched server added", "id", w.ID, "host", w.Host, "port", w.Port)
+	go m.pollWatch(w)
+	return WatchInfo{WatchedServer: w}, nil
+}
+
+func (m *Manager) UpdateWatch(id string, upd WatchedServer) (WatchInfo, error) {
+	if err := upd.validate(); err != nil {
+		return WatchInfo{}, err
+	}
+	m.watch.mu.Lock()
+	if _, ok := m.watch.items[id]; !ok {
+		m.watch.mu.Unlock()
+		return WatchInfo{}, ErrWatchNotFound
+	}
+	upd.ID = id
+	m.watch.items[id] = &upd
+	delete(m.watch.status, id)
+	m.watch.mu.Unlock()
+	if err := m.persistWatches(); err != nil {
+		return WatchInfo{}, err
+	}
+	go m.pollWatch(upd)
+	return WatchInfo{WatchedServer: upd}, nil
+}
+
+func (m *Manager) DeleteWatch(id string) error {
+	m.watch.mu.Lock()
+	if _, ok := m.watch.items[id]; !ok {
+		m.watch.mu.Unlock()
+		return ErrWatchNotFound
+	}
+	delete(m.watch.items, id)
+	delete(m.watch.status, id)
+	m.watch.mu.Unlock()
+	log.Info("watched server removed", "id", id)
+	return m.persistWatches()
+}
+
+// runWatcher pings all watched servers every watchInterval.
+func (m *Manager) runWatcher() {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		m.watch.mu.Lock()
+		list := make([]WatchedServer, 0, len(m.watch.items))
+		for _, w := range m.watch.items {
+			list = append(list, *w)
+		}
+		m.watch.mu.Unlock()
+		for _, w := range list {
+			go m.pollWatch(w)
+		}
+		select {
+		case <-m.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) pollWatch(w WatchedServer) {
+	st := &WatchStatus{CheckedAt: time.Now()}
+	if status, err := query.PingServer(w.Host, w.Port, watchTimeout); err == nil {
+		st.Online = true
+		st.LatencyMs = status.LatencyMs
+		st.Version, st.Protocol = status.Version.Name, status.Version.Protocol
+		st.MOTD = status.MOTD
+		st.Players = &PlayerInfo{Current: status.Players.Online, Max: status.Players.Max}
+		for _, p := range status.Players.Sample {
+			st.Players.Names = append(st.Players.Names, p.Name)
+		}
+		st.LastOnline = &st.CheckedAt
+	} else {
+		st.Error = err.Error()
+	}
+
+	m.watch.mu.Lock()
+	cur, ok := m.watch.items[w.ID]
+	if !ok || *cur != w {
+		// Removed or edited while the ping was in flight
+		m.watch.mu.Unlock()
+		return
+	}
+	prev := m.watch.status[w.ID]
+	if !st.Online && prev != nil {
+		st.LastOnline = prev.LastOnline
+	}
+	m.watch.status[w.ID] = st
+	m.watch.mu.Unlock()
+
+	if prev != nil && prev.Online != st.Online {
+		log.Info("watched server changed state", "id", w.ID, "host", w.Host, "online", st.Online)
+	}
+	m.bus.Publish(events.Event{Type: "watch.status", ServerID: w.ID, Data: WatchInfo{WatchedServer: w, Status: st}})
+}
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// ChatComponent is a Minecraft text component. Servers send the MOTD either as
+// a plain string or as a component tree whose children are in Extra.
+type ChatComponent struct {
+	Text          string          `json:"text"`
+	Translate     string          `json:"translate,omitempty"`
+	Color         string          `json:"color,omitempty"`
+	Bold          *bool           `json:"bold,omitempty"`
+	Italic        *bool           `json:"italic,omitempty"`
+	Underlined    *bool           `json:"underlined,omitempty"`
+	Strikethrough *bool           `json:"strikethrough,omitempty"`
+	Obfuscated    *bool           `json:"obfuscated,omitempty"`
+	Extra         []ChatComponent `json:"extra,omitempty"`
+}
+
+// formatCodeRe matches legacy §-formatting codes
+var formatCodeRe = regexp.MustCompile(`§[0-9a-fk-orA-FK-OR]`)
+
+func (c *ChatComponent) UnmarshalJSON(b []byte) error {
+	switch {
+	case len(b) > 0 && b[0] == '"':
+		*c = ChatComponent{}
+		return json.Unmarshal(b, &c.Text)
+	case len(b) > 0 && b[0] == '[':
+		// An array is a component followed by its siblings
+		var list []ChatComponent
+		if err := json.Unmarshal(b, &list); err != nil {
+			return err
+		}
+		*c = ChatComponent{}
+		if len(list) > 0 {
+			*c = list[0]
+			c.Extra = append(c.Extra, list[1:]...)
+		}
+		return nil
+	}
+	type plain ChatComponent
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*c = ChatComponent(p)
+	return nil
+}
+
+// String returns the plain text of the component tree without formatting codes.
+func (c ChatComponent) String() string {
+	var sb strings.Builder
+	c.writeText(&sb)
+	return formatCodeRe.ReplaceAllString(sb.String(), "")
+}
+
+func (c ChatComponent) writeText(sb *strings.Builder) {
+	if c.Text != "" {
+		sb.WriteString(c.Text)
+	} else {
+		sb.WriteString(c.Translate)
+	}
+	for _, e := range c.Extra {
+		e.writeText(sb)
+	}
+}
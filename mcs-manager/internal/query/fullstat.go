@@ -0,0 +1,174 @@
+package query
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FullStat is the response of a GameSpy4 full-stat query (enable-query=true).
+type FullStat struct {
+	MOTD       string            `json:"motd"`
+	GameType   string            `json:"gameType"`
+	GameID     string            `json:"gameId"`
+	Version    string            `json:"version"`
+	Software   string            `json:"software,omitempty"` // e.g. "Paper on 1.21.1", empty for vanilla
+	Plugins    []string          `json:"plugins,omitempty"`
+	Map        string            `json:"map"`
+	NumPlayers int               `json:"numPlayers"`
+	MaxPlayers int               `json:"maxPlayers"`
+	HostPort   int               `json:"hostPort"`
+	HostIP     string            `json:"hostIp"`
+	Players    []string          `json:"players"`
+	Raw        map[string]string `json:"raw"`
+}
+
+const (
+	queryHandshake = 0x09
+	queryStat      = 0x00
+)
+
+var errInvalidQuery = errors.New("invalid query response")
+
+// QueryFullStat performs the UDP handshake and full-stat request against the
+// query port of a server.
+func QueryFullStat(host string, port int, timeout time.Duration) (*FullStat, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to query port at %s: %w", addr, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var sid [4]byte
+	rand.Read(sid[:])
+	// Minecraft only looks at the low nibble of each session id byte
+	for i := range sid {
+		sid[i] &= 0x0F
+	}
+
+	// Handshake: the server answers with a challenge token as ASCII digits
+	resp, err := queryRoundTrip(conn, queryHandshake, sid, nil)
+	if err != nil {
+		return nil, fmt.Errorf("query handshake failed: %w", err)
+	}
+	token, err := strconv.ParseInt(string(bytes.TrimRight(resp, "\x00")), 10, 32)
+	if err != nil {
+		return nil, errInvalidQuery
+	}
+
+	// Full stat: challenge token followed by four bytes of padding
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint32(payload, uint32(int32(token)))
+	resp, err = queryRoundTrip(conn, queryStat, sid, payload)
+	if err != nil {
+		return nil, fmt.Errorf("query full stat failed: %w", err)
+	}
+	return parseFullStat(resp)
+}
+
+// queryRoundTrip sends one request and returns the response body after type and session id.
+func queryRoundTrip(conn net.Conn, typ byte, sid [4]byte, payload []byte) ([]byte, error) {
+	req := append([]byte{0xFE, 0xFD, typ, sid[0], sid[1], sid[2], sid[3]}, payload...)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	if n < 5 || buf[0] != typ || !bytes.Equal(buf[1:5], sid[:]) {
+		return nil, errInvalidQuery
+	}
+	return buf[5:n], nil
+}
+
+func parseFullStat(b []byte) (*FullStat, error) {
+	// "splitnum\x00\x80\x00" precedes the key/value section
+	const kvStart = "splitnum\x00\x80\x00"
+	if !bytes.HasPrefix(b, []byte(kvStart)) {
+		return nil, errInvalidQuery
+	}
+	b = b[len(kvStart):]
+
+	raw := map[string]string{}
+	for {
+		key, rest, ok := cutNul(b)
+		if !ok {
+			return nil, errInvalidQuery
+		}
+		b = rest
+		if key == "" {
+			break
+		}
+		val, rest, ok := cutNul(b)
+		if !ok {
+			return nil, errInvalidQuery
+		}
+		b = rest
+		raw[key] = val
+	}
+
+	// "\x01player_\x00\x00" precedes the player names
+	const playerStart = "\x01player_\x00\x00"
+	players := []string{}
+	if bytes.HasPrefix(b, []byte(playerStart)) {
+		b = b[len(playerStart):]
+		for {
+			name, rest, ok := cutNul(b)
+			if !ok || name == "" {
+				break
+			}
+			players = append(players, name)
+			b = rest
+		}
+	}
+
+	st := &FullStat{
+		MOTD:     raw["hostname"],
+		GameType: raw["gametype"],
+		GameID:   raw["game_id"],
+		Version:  raw["version"],
+		Map:      raw["map"],
+		HostIP:   raw["hostip"],
+		Players:  players,
+		Raw:      raw,
+	}
+	st.NumPlayers, _ = strconv.Atoi(raw["numplayers"])
+	st.MaxPlayers, _ = strconv.Atoi(raw["maxplayers"])
+	st.HostPort, _ = strconv.Atoi(raw["hostport"])
+	st.Software, st.Plugins = parsePlugins(raw["plugins"])
+	return st, nil
+}
+
+// parsePlugins splits "Paper on 1.21.1: WorldEdit 7.3; LuckPerms 5.4" into the
+// server software and its plugin list.
+func parsePlugins(s string) (string, []string) {
+	software, list, found := strings.Cut(s, ":")
+	if !found {
+		return strings.TrimSpace(s), nil
+	}
+	var plugins []string
+	for _, p := range strings.Split(list, ";") {
+		if p = strings.TrimSpace(p); p != "" {
+			plugins = append(plugins, p)
+		}
+	}
+	return strings.TrimSpace(software), plugins
+}
+
+func cutNul(b []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(b[:i]), b[i+1:], true
+}
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"bytes"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+// vanillaFullStat is a full-stat response of a vanilla 1.21.1 server with
+// two players online, without the leading type and session id.
+const vanillaFullStat = "splitnum\x00\x80\x00" +
+	"hostname\x00A Minecraft Server\x00gametype\x00SMP\x00game_id\x00MINECRAFT\x00" +
+	"version\x001.21.1\x00plugins\x00\x00map\x00world\x00numplayers\x002\x00" +
+	"maxplayers\x0020\x00hostport\x0025565\x00hostip\x00127.0.0.1\x00\x00" +
+	"\x01player_\x00\x00Steve\x00Alex\x00\x00"
+
+func TestParseFullStat(t *testing.T) {
+	st, err := parseFullStat([]byte(vanillaFullStat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.MOTD != "A Minecraft Server" || st.GameType != "SMP" || st.GameID != "MINECRAFT" || st.Version != "1.21.1" || st.Map != "world" {
+		t.Errorf("stat = %+v", st)
+	}
+	if st.NumPlayers != 2 || st.MaxPlayers != 20 || st.HostPort != 25565 || st.HostIP != "127.0.0.1" {
+		t.Errorf("numbers = %d/%d %s:%d", st.NumPlayers, st.MaxPlayers, st.HostIP, st.HostPort)
+	}
+	if !slices.Equal(st.Players, []string{"Steve", "Alex"}) {
+		t.Errorf("players = %q", st.Players)
+	}
+	if st.Software != "" || st.Plugins != nil {
+		t.Errorf("vanilla server reported software %q, plugins %q", st.Software, st.Plugins)
+	}
+	if len(st.Raw) != 10 {
+		t.Errorf("raw = %v", st.Raw)
+	}
+}
+
+func TestParseFullStatPlugins(t *testing.T) {
+	resp := "splitnum\x00\x80\x00hostname\x00Paper\x00numplayers\x000\x00" +
+		"plugins\x00Paper on 1.21.1-R0.1-SNAPSHOT: WorldEdit 7.3.0; LuckPerms 5.4.102\x00\x00" +
+		"\x01player_\x00\x00\x00"
+	st, err := parseFullStat([]byte(resp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Software != "Paper on 1.21.1-R0.1-SNAPSHOT" || !slices.Equal(st.Plugins, []string{"WorldEdit 7.3.0", "LuckPerms 5.4.102"}) {
+		t.Errorf("software %q, plugins %q", st.Software, st.Plugins)
+	}
+	if len(st.Players) != 0 {
+		t.Errorf("players = %q", st.Players)
+	}
+}
+
+func TestParseFullStatInvalid(t *testing.T) {
+	for _, resp := range []string{
+		"",
+		"hostname\x00A Minecraft Server\x00\x00",
+		// Padding without the 0x80 byte
+		"splitnum\x00\x00hostname\x00x\x00\x00",
+		"splitnum\x00\x80\x00hostname\x00unterminated",
+		"splitnum\x00\x80\x00hostname",
+	} {
+		if _, err := parseFullStat([]byte(resp)); err == nil {
+			t.Errorf("parseFullStat(%q): expected an error", resp)
+		}
+	}
+}
+
+func TestQueryFullStat(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req := buf[:n]
+			if n < 7 || req[0] != 0xFE || req[1] != 0xFD {
+				continue
+			}
+			head := append([]byte{req[2]}, req[3:7]...)
+			switch req[2] {
+			case queryHandshake:
+				pc.WriteTo(append(head, "9513307\x00"...), addr)
+			case queryStat:
+				// Challenge token and padding
+				if n != 15 || !bytes.Equal(req[7:11], []byte{0x00, 0x91, 0x29, 0x5B}) {
+					continue
+				}
+				pc.WriteTo(append(head, vanillaFullStat...), addr)
+			}
+		}
+	}()
+
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	st, err := QueryFullStat("127.0.0.1", port, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.MOTD != "A Minecraft Server" || st.HostPort != 25565 || len(st.Players) != 2 {
+		t.Errorf("stat = %+v", st)
+	}
+}
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// legacyProtocol is the protocol version 1.6.4 clients announce in the ping
+const legacyProtocol = 78
+
+// pingLegacy performs the 1.6 server list ping (0xFE 0x01 0xFA "MC|PingHost").
+// Servers from 1.4 to 1.6 answer with a "§1" prefixed response, older ones with
+// "motd§online§max".
+func pingLegacy(addr, host string, port int, deadline time.Time) (*ServerStatus, error) {
+	conn, err := net.DialTimeout("tcp", addr, time.Until(deadline))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server at %s: %w", addr, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	data := &bytes.Buffer{}
+	data.WriteByte(legacyProtocol)
+	writeUTF16(data, host)
+	binary.Write(data, binary.BigEndian, int32(port))
+
+	req := &bytes.Buffer{}
+	req.Write([]byte{0xFE, 0x01, 0xFA})
+	writeUTF16(req, "MC|PingHost")
+	binary.Write(req, binary.BigEndian, uint16(data.Len()))
+	req.Write(data.Bytes())
+	sent := time.Now()
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to send legacy ping: %w", err)
+	}
+
+	var hdr [3]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read legacy response: %w", err)
+	}
+	if hdr[0] != 0xFF {
+		return nil, errInvalidPacket
+	}
+	chars := make([]uint16, binary.BigEndian.Uint16(hdr[1:]))
+	if err := binary.Read(conn, binary.BigEndian, chars); err != nil {
+		return nil, fmt.Errorf("failed to read legacy response: %w", err)
+	}
+	status, err := parseLegacy(string(utf16.Decode(chars)))
+	if err != nil {
+		return nil, err
+	}
+	status.LatencyMs = time.Since(sent).Milliseconds()
+	return status, nil
+}
+
+func parseLegacy(s string) (*ServerStatus, error) {
+	st := &ServerStatus{Legacy: true}
+	if strings.HasPrefix(s, "§1\x00") {
+		f := strings.Split(s, "\x00")
+		if len(f) != 6 {
+			return nil, errInvalidPacket
+		}
+		st.Version.Protocol, _ = strconv.Atoi(f[1])
+		st.Version.Name = f[2]
+		st.Description.Text = f[3]
+		st.Players.Online, _ = strconv.Atoi(f[4])
+		st.Players.Max, _ = strconv.Atoi(f[5])
+	} else {
+		// Beta 1.8 to 1.3: the MOTD itself may not contain §
+		f := strings.Split(s, "§")
+		if len(f) < 3 {
+			return nil, errInvalidPacket
+		}
+		st.Description.Text = strings.Join(f[:len(f)-2], "§")
+		st.Players.Online, _ = strconv.Atoi(f[len(f)-2])
+		st.Players.Max, _ = strconv.Atoi(f[len(f)-1])
+	}
+	st.MOTD = st.Description.String()
+	return st, nil
+}
+
+func writeUTF16(buf *bytes.Buffer, s string) {
+	u := utf16.Encode([]rune(s))
+	binary.Write(buf, binary.BigEndian, uint16(len(u)))
+	binary.Write(buf, binary.BigEndian, u)
+}
@@ -1,20 +1,32 @@
 package query
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // ServerStatus represents the JSON response from Minecraft server
 type ServerStatus struct {
-	Version Version `json:"version"`
-	Players Players `json:"players"`
-	Description DescriptionData `json:"description"`
+	Version     Version       `json:"version"`
+	Players     Players       `json:"players"`
+	Description ChatComponent `json:"description"`
+	// Favicon is a "data:image/png;base64," URI, see FaviconPNG
+	Favicon string `json:"favicon,omitempty"`
+
+	// Filled in by the client, not part of the server's response
+	MOTD      string `json:"motd"`
+	LatencyMs int64  `json:"latencyMs"`
+	Legacy    bool   `json:"legacy,omitempty"`
 }
 
 type Version struct {
@@ -23,78 +35,145 @@ type Version struct {
 }
 
 type Players struct {
-	Max    int `json:"max"`
-	Online int `json:"online"`
+	Max    int            `json:"max"`
+	Online int            `json:"online"`
+	Sample []PlayerSample `json:"sample,omitempty"`
 }
 
-type DescriptionData struct {
-	Text string `json:"text"`
+type PlayerSample struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
 }
 
-// PingServer sends a status ping to a Minecraft server and gets player info
+const (
+	defaultPort = 25565
+	// -1 tells the server we are only asking which version it runs
+	pingProtocol  = -1
+	maxPacketSize = 2 << 20
+)
+
+var errInvalidPacket = errors.New("invalid status packet")
+
+// PingServer sends a status ping to a Minecraft server and gets player info.
+// With port 0 the _minecraft._tcp SRV record of host is used, falling back to
+// 25565. Servers older than 1.7 are answered via the legacy 0xFE ping.
 func PingServer(host string, port int, timeout time.Duration) (*ServerStatus, error) {
-	addr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	conn, err := net.DialTimeout("tcp", addr, timeout)
+	deadline := time.Now().Add(timeout)
+	target := host
+	if port == 0 {
+		target, port = resolveSRV(host)
+	}
+	addr := net.JoinHostPort(target, strconv.Itoa(port))
+
+	status, err := pingModern(addr, host, port, deadline)
+	if err == nil {
+		return status, nil
+	}
+	var netErr net.Error
+	if (errors.As(err, &netErr) && netErr.Timeout()) || isDialError(err) {
+		return nil, err
+	}
+	// Pre-1.7 servers drop the connection on the modern handshake
+	if legacy, lerr := pingLegacy(addr, host, port, deadline); lerr == nil {
+		return legacy, nil
+	}
+	return nil, err
+}
+
+func pingModern(addr, host string, port int, deadline time.Time) (*ServerStatus, error) {
+	conn, err := net.DialTimeout("tcp", addr, time.Until(deadline))
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to server at %s: %w", addr, err)
+		return nil, &dialError{fmt.Errorf("failed to connect to server at %s: %w", addr, err)}
 	}
 	defer conn.Close()
-
-	// Set read deadline
-	conn.SetReadDeadline(time.Now().Add(timeout))
+	conn.SetDeadline(deadline)
+	r := bufio.NewReader(conn)
 
 	// Handshake packet
-	handshakeData := bytes.NewBuffer([]byte{})
-	
-	// Protocol version (use 762 for 1.20.x compatibility)
-	writeVarInt(handshakeData, 762)
-	writeString(handshakeData, host)
-	binary.Write(handshakeData, binary.BigEndian, uint16(port))
-	writeVarInt(handshakeData, 1) // Next state: status
-	
-	// Wrap with packet ID
-	packet := bytes.NewBuffer([]byte{})
-	writeVarInt(packet, 0) // Handshake packet ID
-	packet.Write(handshakeData.Bytes())
-	
-	// Write handshake packet
-	if err := writePacket(conn, packet.Bytes()); err != nil {
+	handshake := &bytes.Buffer{}
+	writeVarInt(handshake, 0x00)
+	writeVarInt(handshake, pingProtocol)
+	writeString(handshake, host)
+	binary.Write(handshake, binary.BigEndian, uint16(port))
+	writeVarInt(handshake, 1) // Next state: status
+	if err := writePacket(conn, handshake.Bytes()); err != nil {
 		return nil, fmt.Errorf("failed to send handshake: %w", err)
 	}
 
 	// Status request packet (empty)
-	statusPacket := bytes.NewBuffer([]byte{})
-	writeVarInt(statusPacket, 0) // Status request packet ID
-	if err := writePacket(conn, statusPacket.Bytes()); err != nil {
+	sent := time.Now()
+	if err := writePacket(conn, []byte{0x00}); err != nil {
 		return nil, fmt.Errorf("failed to send status request: %w", err)
 	}
-
-	// Read response
-	statusResponse, err := readPacket(conn)
+	id, body, err := readPacket(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read status response: %w", err)
 	}
-
-	// Skip packet ID (first VarInt)
-	reader := bytes.NewReader(statusResponse)
-	readVarInt(reader) // Skip packet ID (0x00 for status response)
-
-	// Read JSON string length and data
-	jsonLen := readVarInt(reader)
-	jsonData := make([]byte, jsonLen)
-	if _, err := io.ReadFull(reader, jsonData); err != nil {
-		return nil, fmt.Errorf("failed to read JSON data: %w", err)
+	latency := time.Since(sent)
+	if id != 0x00 {
+		return nil, errInvalidPacket
+	}
+	jsonLen, err := readVarInt(body)
+	if err != nil || jsonLen < 0 || int(jsonLen) > body.Len() {
+		return nil, errInvalidPacket
 	}
+	jsonData := make([]byte, jsonLen)
+	io.ReadFull(body, jsonData)
 
-	// Parse JSON
 	var status ServerStatus
 	if err := json.Unmarshal(jsonData, &status); err != nil {
 		return nil, fmt.Errorf("failed to parse status JSON: %w", err)
 	}
-
+	status.MOTD = status.Description.String()
+
+	// Ping/pong gives a cleaner round trip than the status response, which the
+	// server may have to build first. Servers that skip it keep the status latency.
+	ping := &bytes.Buffer{}
+	writeVarInt(ping, 0x01)
+	binary.Write(ping, binary.BigEndian, sent.UnixMilli())
+	sent = time.Now()
+	if err := writePacket(conn, ping.Bytes()); err == nil {
+		if id, _, err := readPacket(r); err == nil && id == 0x01 {
+			latency = time.Since(sent)
+		}
+	}
+	status.LatencyMs = latency.Milliseconds()
 	return &status, nil
 }
 
+// FaviconPNG decodes the server icon.
+func (s *ServerStatus) FaviconPNG() ([]byte, error) {
+	data, ok := strings.CutPrefix(s.Favicon, "data:image/png;base64,")
+	if !ok {
+		return nil, errors.New("no png favicon")
+	}
+	// Some servers include line breaks in the base64 data
+	data = strings.NewReplacer("\n", "", "\r", "").Replace(data)
+	return base64.StdEncoding.DecodeString(data)
+}
+
+// resolveSRV looks up _minecraft._tcp.<host> the way the game client does.
+func resolveSRV(host string) (string, int) {
+	if net.ParseIP(host) != nil {
+		return host, defaultPort
+	}
+	_, addrs, err := net.LookupSRV("minecraft", "tcp", host)
+	if err != nil || len(addrs) == 0 {
+		return host, defaultPort
+	}
+	return strings.TrimSuffix(addrs[0].Target, "."), int(addrs[0].Port)
+}
+
+type dialError struct{ err error }
+
+func (e *dialError) Error() string { return e.err.Error() }
+func (e *dialError) Unwrap() error { return e.err }
+
+func isDialError(err error) bool {
+	var d *dialError
+	return errors.As(err, &d)
+}
+
 // Helper functions for reading/writing VarInt and other types
 
 func writeVarInt(buf *bytes.Buffer, value int32) {
@@ -112,21 +191,19 @@ func writeVarInt(buf *bytes.Buffer, value int32) {
 	}
 }
 
-func readVarInt(r io.Reader) int32 {
-	result := int32(0)
-	shift := 0
-	for {
-		b := make([]byte, 1)
-		if _, err := io.ReadFull(r, b); err != nil {
-			return 0
+func readVarInt(r io.ByteReader) (int32, error) {
+	var result uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
 		}
-		result |= int32(b[0]&0x7F) << shift
-		if b[0]&0x80 == 0 {
-			break
+		result |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(result), nil
 		}
-		shift += 7
 	}
-	return result
+	return 0, errors.New("varint too long")
 }
 
 func writeString(buf *bytes.Buffer, s string) {
@@ -137,35 +214,30 @@ func writeString(buf *bytes.Buffer, s string) {
 
 func writePacket(conn net.Conn, data []byte) error {
 	// Write packet length as VarInt, then packet data
-	lengthBuf := bytes.NewBuffer([]byte{})
-	writeVarInt(lengthBuf, int32(len(data)))
-	
-	fullPacket := append(lengthBuf.Bytes(), data...)
-	_, err := conn.Write(fullPacket)
+	packet := &bytes.Buffer{}
+	writeVarInt(packet, int32(len(data)))
+	packet.Write(data)
+	_, err := conn.Write(packet.Bytes())
 	return err
 }
 
-func readPacket(conn net.Conn) ([]byte, error) {
-	// Read packet length
-	lengthBuf := make([]byte, 5) // Max 5 bytes for VarInt
-	n, err := conn.Read(lengthBuf)
+// readPacket reads one length-prefixed packet and returns its id and remaining body.
+func readPacket(r *bufio.Reader) (int32, *bytes.Reader, error) {
+	length, err := readVarInt(r)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
-
-	reader := bytes.NewReader(lengthBuf[:n])
-	length := readVarInt(reader)
-
-	// Read packet data
-	packetData := make([]byte, length)
-	totalRead := 0
-	for totalRead < int(length) {
-		n, err := conn.Read(packetData[totalRead:])
-		if err != nil {
-			return nil, err
-		}
-		totalRead += n
+	if length <= 0 || length > maxPacketSize {
+		return 0, nil, errInvalidPacket
 	}
-
-	return packetData, nil
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	body := bytes.NewReader(data)
+	id, err := readVarInt(body)
+	if err != nil {
+		return 0, nil, errInvalidPacket
+	}
+	return id, body, nil
 }
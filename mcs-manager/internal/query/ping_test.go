@@ -0,0 +1,248 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// fakeServer accepts connections on a local port and hands each to handle.
+func fakeServer(t *testing.T, handle func(net.Conn)) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				c.SetDeadline(time.Now().Add(5 * time.Second))
+				handle(c)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// modernServer answers the 1.7+ status handshake with status and echoes the
+// ping payload after pongDelay.
+func modernServer(t *testing.T, status any, pongDelay time.Duration) int {
+	b, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fakeServer(t, func(c net.Conn) {
+		r := bufio.NewReader(c)
+		id, body, err := readPacket(r)
+		if err != nil || id != 0x00 {
+			return
+		}
+		// protocol, host, port, next state
+		if _, err := readVarInt(body); err != nil {
+			return
+		}
+		n, _ := readVarInt(body)
+		body.Seek(int64(n)+2, io.SeekCurrent)
+		if next, _ := readVarInt(body); next != 1 {
+			return
+		}
+		if id, _, err := readPacket(r); err != nil || id != 0x00 {
+			return
+		}
+		resp := &bytes.Buffer{}
+		writeVarInt(resp, 0x00)
+		writeString(resp, string(b))
+		if writePacket(c, resp.Bytes()) != nil {
+			return
+		}
+		id, body, err = readPacket(r)
+		if err != nil || id != 0x01 {
+			return
+		}
+		time.Sleep(pongDelay)
+		pong := &bytes.Buffer{}
+		writeVarInt(pong, 0x01)
+		io.Copy(pong, body)
+		writePacket(c, pong.Bytes())
+	})
+}
+
+// legacyServer drops the modern handshake like a pre-1.7 server and answers
+// the 0xFE ping with reply.
+func legacyServer(t *testing.T, reply string) int {
+	return fakeServer(t, func(c net.Conn) {
+		var hdr [3]byte
+		if _, err := io.ReadFull(c, hdr[:]); err != nil || hdr != [3]byte{0xFE, 0x01, 0xFA} {
+			return
+		}
+		var n uint16
+		binary.Read(c, binary.BigEndian, &n)
+		io.CopyN(io.Discard, c, int64(n)*2) // "MC|PingHost"
+		binary.Read(c, binary.BigEndian, &n)
+		io.CopyN(io.Discard, c, int64(n))
+
+		u := utf16.Encode([]rune(reply))
+		resp := &bytes.Buffer{}
+		resp.WriteByte(0xFF)
+		binary.Write(resp, binary.BigEndian, uint16(len(u)))
+		binary.Write(resp, binary.BigEndian, u)
+		c.Write(resp.Bytes())
+	})
+}
+
+func TestPingModern(t *testing.T) {
+	port := modernServer(t, map[string]any{
+		"version":     map[string]any{"name": "1.21.1", "protocol": 767},
+		"players":     map[string]any{"max": 20, "online": 2, "sample": []map[string]string{{"name": "Alex", "id": "ec561538-f3fd-461d-aff5-086b22154bce"}}},
+		"description": "A Minecraft Server",
+	}, 50*time.Millisecond)
+
+	st, err := PingServer("127.0.0.1", port, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Version.Name != "1.21.1" || st.Version.Protocol != 767 {
+		t.Errorf("version = %+v", st.Version)
+	}
+	if st.Players.Online != 2 || st.Players.Max != 20 || len(st.Players.Sample) != 1 || st.Players.Sample[0].Name != "Alex" {
+		t.Errorf("players = %+v", st.Players)
+	}
+	if st.MOTD != "A Minecraft Server" {
+		t.Errorf("motd = %q", st.MOTD)
+	}
+	if st.Legacy {
+		t.Error("modern server reported as legacy")
+	}
+	// The latency comes from the ping/pong round trip
+	if st.LatencyMs < 50 {
+		t.Errorf("latency = %dms, want at least the 50ms pong delay", st.LatencyMs)
+	}
+}
+
+func TestPingChatComponentMOTD(t *testing.T) {
+	port := modernServer(t, map[string]any{
+		"version": map[string]any{"name": "Paper 1.21.1", "protocol": 767},
+		"players": map[string]any{"max": 100, "online": 0},
+		"description": map[string]any{
+			"text":  "§aWelcome ",
+			"extra": []any{map[string]any{"text": "to ", "bold": true}, map[string]any{"translate": "Obsidian", "color": "gold"}, "!"},
+		},
+	}, 0)
+
+	st, err := PingServer("127.0.0.1", port, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.MOTD != "Welcome to Obsidian!" {
+		t.Errorf("motd = %q", st.MOTD)
+	}
+	if len(st.Description.Extra) != 3 || st.Description.Extra[0].Bold == nil || !*st.Description.Extra[0].Bold {
+		t.Errorf("description = %+v", st.Description)
+	}
+}
+
+func TestPingFavicon(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nnot really an image")
+	enc := base64.StdEncoding.EncodeToString(png)
+	port := modernServer(t, map[string]any{
+		"version":     map[string]any{"name": "1.21.1", "protocol": 767},
+		"players":     map[string]any{"max": 20, "online": 0},
+		"description": "icon",
+		// Some servers wrap the base64 data
+		"favicon": "data:image/png;base64," + enc[:10] + "\n" + enc[10:],
+	}, 0)
+
+	st, err := PingServer("127.0.0.1", port, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.FaviconPNG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, png) {
+		t.Errorf("favicon = %q, want %q", got, png)
+	}
+
+	if _, err := (&ServerStatus{}).FaviconPNG(); err == nil {
+		t.Error("expected an error without favicon")
+	}
+}
+
+func TestPingLegacyFallback(t *testing.T) {
+	port := legacyServer(t, "§1\x00127\x001.6.4\x00A §6legacy§r server\x003\x0020")
+
+	st, err := PingServer("127.0.0.1", port, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Legacy {
+		t.Error("expected a legacy response")
+	}
+	if st.Version.Name != "1.6.4" || st.Version.Protocol != 127 {
+		t.Errorf("version = %+v", st.Version)
+	}
+	if st.Players.Online != 3 || st.Players.Max != 20 {
+		t.Errorf("players = %+v", st.Players)
+	}
+	if st.MOTD != "A legacy server" {
+		t.Errorf("motd = %q", st.MOTD)
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	tests := []struct {
+		in          string
+		motd        string
+		online, max int
+		wantErr     bool
+	}{
+		{in: "§1\x0078\x001.6.4\x00Hello\x001\x0010", motd: "Hello", online: 1, max: 10},
+		// Beta 1.8 to 1.3
+		{in: "An old server§4§16", motd: "An old server", online: 4, max: 16},
+		{in: "§1\x0078\x001.6.4", wantErr: true},
+		{in: "no separators", wantErr: true},
+	}
+	for _, tt := range tests {
+		st, err := parseLegacy(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLegacy(%q): expected an error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseLegacy(%q): %v", tt.in, err)
+			continue
+		}
+		if st.MOTD != tt.motd || st.Players.Online != tt.online || st.Players.Max != tt.max {
+			t.Errorf("parseLegacy(%q) = %q %d/%d", tt.in, st.MOTD, st.Players.Online, st.Players.Max)
+		}
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	if _, err := PingServer("127.0.0.1", port, time.Second); err == nil {
+		t.Error("expected an error for a closed port")
+	}
+}
@@ -0,0 +1,148 @@
+// Package rcon implements the Source RCON protocol as spoken by Minecraft servers.
+package rcon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	typeResponse = 0
+	typeCommand  = 2
+	typeAuthResp = 2
+	typeAuth     = 3
+
+	// Minecraft rejects request bodies longer than this
+	maxCommandLen = 1446
+	// responses are split into packets of at most 4096 bytes of body
+	maxPacketLen = 4096 + 10
+)
+
+var (
+	ErrAuthFailed      = errors.New("rcon authentication failed")
+	ErrCommandTooLong  = errors.New("rcon command too long")
+	ErrInvalidResponse = errors.New("invalid rcon response")
+)
+
+// Client is an authenticated RCON connection. It is safe for concurrent use;
+// commands are executed one at a time.
+type Client struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	nextID  int32
+	timeout time.Duration
+}
+
+// Dial connects to addr and authenticates with password.
+func Dial(addr, password string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to rcon at %s: %w", addr, err)
+	}
+	c := &Client{conn: conn, timeout: timeout}
+	if err := c.auth(password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) auth(password string) error {
+	id := c.id()
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := c.write(id, typeAuth, password); err != nil {
+		return err
+	}
+	for {
+		rid, typ, _, err := c.read()
+		if err != nil {
+			return err
+		}
+		// Source servers send an empty response value ahead of the auth response
+		if typ != typeAuthResp {
+			continue
+		}
+		if rid == -1 || rid != id {
+			return ErrAuthFailed
+		}
+		return nil
+	}
+}
+
+// Exec runs a command and returns its output. Output that spans several packets
+// is collected by sending an empty follow-up packet and reading until its
+// response arrives.
+func (c *Client) Exec(cmd string) (string, error) {
+	if len(cmd) > maxCommandLen {
+		return "", ErrCommandTooLong
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	id, end := c.id(), c.id()
+	if err := c.write(id, typeCommand, cmd); err != nil {
+		return "", err
+	}
+	if err := c.write(end, typeResponse, ""); err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	for {
+		rid, _, body, err := c.read()
+		if err != nil {
+			return "", err
+		}
+		switch rid {
+		case id:
+			out.WriteString(body)
+		case end:
+			return out.String(), nil
+		case -1:
+			return "", ErrAuthFailed
+		}
+	}
+}
+
+func (c *Client) Close() error { return c.conn.Close() }
+
+func (c *Client) id() int32 {
+	c.nextID++
+	return c.nextID
+}
+
+func (c *Client) write(id, typ int32, body string) error {
+	buf := bytes.NewBuffer(make([]byte, 0, 14+len(body)))
+	binary.Write(buf, binary.LittleEndian, int32(10+len(body)))
+	binary.Write(buf, binary.LittleEndian, id)
+	binary.Write(buf, binary.LittleEndian, typ)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+	_, err := c.conn.Write(buf.Bytes())
+	return err
+}
+
+func (c *Client) read() (id, typ int32, body string, err error) {
+	var size int32
+	if err = binary.Read(c.conn, binary.LittleEndian, &size); err != nil {
+		return
+	}
+	if size < 10 || size > maxPacketLen {
+		err = ErrInvalidResponse
+		return
+	}
+	p := make([]byte, size)
+	if _, err = io.ReadFull(c.conn, p); err != nil {
+		return
+	}
+	id = int32(binary.LittleEndian.Uint32(p[0:4]))
+	typ = int32(binary.LittleEndian.Uint32(p[4:8]))
+	body = string(bytes.TrimRight(p[8:], "\x00"))
+	return
+}
@@ -0,0 +1,170 @@
+package rcon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type packet struct {
+	id, typ int32
+	body    string
+}
+
+func readPacket(r io.Reader) (packet, error) {
+	var size int32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return packet{}, err
+	}
+	p := make([]byte, size)
+	if _, err := io.ReadFull(r, p); err != nil {
+		return packet{}, err
+	}
+	return packet{
+		id:   int32(binary.LittleEndian.Uint32(p[0:4])),
+		typ:  int32(binary.LittleEndian.Uint32(p[4:8])),
+		body: string(bytes.TrimRight(p[8:], "\x00")),
+	}, nil
+}
+
+func writePacket(w io.Writer, p packet) {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, int32(10+len(p.body)))
+	binary.Write(buf, binary.LittleEndian, p.id)
+	binary.Write(buf, binary.LittleEndian, p.typ)
+	buf.WriteString(p.body)
+	buf.Write([]byte{0, 0})
+	w.Write(buf.Bytes())
+}
+
+// fakeServer speaks RCON like a Minecraft server with the given password.
+// Commands are answered by reply, which may split the output into several
+// packets.
+func fakeServer(t *testing.T, password string, reply func(cmd string) []string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				c.SetDeadline(time.Now().Add(5 * time.Second))
+				authed := false
+				for {
+					p, err := readPacket(c)
+					if err != nil {
+						return
+					}
+					switch {
+					case p.typ == typeAuth:
+						// Source servers send an empty response value first
+						writePacket(c, packet{id: p.id, typ: typeResponse})
+						if p.body != password {
+							writePacket(c, packet{id: -1, typ: typeAuthResp})
+							return
+						}
+						authed = true
+						writePacket(c, packet{id: p.id, typ: typeAuthResp})
+					case !authed:
+						writePacket(c, packet{id: -1, typ: typeResponse})
+					case p.typ == typeCommand:
+						for _, part := range reply(p.body) {
+							writePacket(c, packet{id: p.id, typ: typeResponse, body: part})
+						}
+					default:
+						// The end marker, answered once all output is sent
+						writePacket(c, packet{id: p.id, typ: typeResponse})
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestExec(t *testing.T) {
+	addr := fakeServer(t, "secret", func(cmd string) []string {
+		switch cmd {
+		case "list":
+			return []string{"There are 1 of a max of 20 players online: Steve"}
+		case "help":
+			// Longer output arrives in several packets
+			return []string{strings.Repeat("a", 4096), strings.Repeat("b", 4096), "c"}
+		case "say hi":
+			return []string{""}
+		}
+		return []string{"Unknown command"}
+	})
+	c, err := Dial(addr, "secret", 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tests := []struct{ cmd, want string }{
+		{"list", "There are 1 of a max of 20 players online: Steve"},
+		{"help", strings.Repeat("a", 4096) + strings.Repeat("b", 4096) + "c"},
+		{"say hi", ""},
+	}
+	for _, tt := range tests {
+		got, err := c.Exec(tt.cmd)
+		if err != nil {
+			t.Errorf("Exec(%q): %v", tt.cmd, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Exec(%q) = %d bytes, want %d", tt.cmd, len(got), len(tt.want))
+		}
+	}
+
+	if _, err := c.Exec(strings.Repeat("x", maxCommandLen+1)); !errors.Is(err, ErrCommandTooLong) {
+		t.Errorf("long command: %v, want ErrCommandTooLong", err)
+	}
+}
+
+func TestAuthFailed(t *testing.T) {
+	addr := fakeServer(t, "secret", func(string) []string { return nil })
+	if _, err := Dial(addr, "wrong", 2*time.Second); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Dial with wrong password: %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestExecUnauthenticated(t *testing.T) {
+	addr := fakeServer(t, "secret", func(string) []string { return []string{"ok"} })
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A connection the server does not consider authenticated
+	c := &Client{conn: conn, timeout: 2 * time.Second}
+	defer c.Close()
+	if _, err := c.Exec("list"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Exec without auth: %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestInvalidPacketSize(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		readPacket(server)
+		binary.Write(server, binary.LittleEndian, int32(maxPacketLen+1))
+	}()
+	c := &Client{conn: client, timeout: 2 * time.Second}
+	if err := c.auth("secret"); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("oversized packet: %v, want ErrInvalidResponse", err)
+	}
+}
@@ -9,13 +9,73 @@ const (
 )
 
 type ServerConfig struct {
-	ID       string     `json:"id"`
-	Name     string     `json:"name"`
-	Type     ServerType `json:"type"`
-	Version  string     `json:"version"`
-	Port     int        `json:"port"`
-	MemoryMB int        `json:"memoryMb"`
-	Path     string     `json:"path"`
-	Eula     bool       `json:"eula"`
-	JarURL   string     `json:"jarUrl"`
+	ID            string         `json:"id"`
+	Name          string         `json:"name"`
+	Type          ServerType     `json:"type"`
+	Version       string         `json:"version"`
+	Port          int            `json:"port"`
+	MemoryMB      int            `json:"memoryMb"`
+	Path          string         `json:"path"`
+	Eula          bool           `json:"eula"`
+	JarURL        string         `json:"jarUrl"`
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+	// StopTimeoutSec is how long "stop" may take before the process is terminated
+	StopTimeoutSec int `json:"stopTimeoutSec,omitempty"`
+	// StartTimeoutSec is how long the server may take to finish loading before the start fails
+	StartTimeoutSec int `json:"startTimeoutSec,omitempty"`
+	// AutoStart servers are started when the manager boots, lowest AutoStartOrder first
+	AutoStart      bool `json:"autoStart,omitempty"`
+	AutoStartOrder int  `json:"autoStartOrder,omitempty"`
+	// AutoStartDelaySec is waited before this server is auto-started
+	AutoStartDelaySec int `json:"autoStartDelaySec,omitempty"`
+	// JavaPath is the java binary to run; otherwise a discovered runtime with
+	// major version JavaVersion, otherwise java from PATH
+	JavaPath    string `json:"javaPath,omitempty"`
+	JavaVersion int    `json:"javaVersion,omitempty"`
+	// MinMemoryMB sets -Xms, JVMPreset adds a tuned flag set ("aikar", "zgc")
+	MinMemoryMB int      `json:"minMemoryMb,omitempty"`
+	JVMPreset   string   `json:"jvmPreset,omitempty"`
+	JVMArgs     []string `json:"jvmArgs,omitempty"`
+	// ServerArgs are passed to the server after nogui
+	ServerArgs []string `json:"serverArgs,omitempty"`
+	// Env is added to the manager's environment for the server and its hooks;
+	// PATH, LD_*, DYLD_* and the JVM option variables are rejected
+	Env   map[string]string `json:"env,omitempty"`
+	Hooks *Hooks            `json:"hooks,omitempty"`
+	// Resource limits applied through a cgroup v2 per server, when available.
+	// CPUMax is in CPUs (1.5 = one and a half cores), IOWeight ranges 1-10000.
+	CPUMax      float64 `json:"cpuMax,omitempty"`
+	MemoryMaxMB int     `json:"memoryMaxMb,omitempty"`
+	IOWeight    int     `json:"ioWeight,omitempty"`
+}
+
+// Hooks are shell commands run in the server directory with the manager's
+// privileges, so only admins may set them. A failing pre-start hook aborts
+// the start.
+type Hooks struct {
+	PreStart string `json:"preStart,omitempty"`
+	PostStop string `json:"postStop,omitempty"`
+	// TimeoutSec limits each hook, default 300
+	TimeoutSec int `json:"timeoutSec,omitempty"`
+}
+
+type RestartMode string
+
+const (
+	RestartNever     RestartMode = "never"
+	RestartOnFailure RestartMode = "on-failure"
+	RestartAlways    RestartMode = "always"
+)
+
+// RestartPolicy controls automatic restarts after the server process exits
+// without being stopped through the manager. Zero values fall back to defaults.
+type RestartPolicy struct {
+	Mode RestartMode `json:"mode"`
+	// MaxRetries limits consecutive restart attempts, 0 means unlimited
+	MaxRetries    int `json:"maxRetries"`
+	BackoffSec    int `json:"backoffSec"`
+	MaxBackoffSec int `json:"maxBackoffSec"`
+	// Give up after CrashLoopCount crashes within CrashLoopWindowMin minutes
+	CrashLoopCount     int `json:"crashLoopCount"`
+	CrashLoopWindowMin int `json:"crashLoopWindowMin"`
 }
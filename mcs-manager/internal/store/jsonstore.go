@@ -9,37 +9,93 @@ import (
 )
 
 type jsonStore struct {
-	root string
-	file string
+	root      string
+	file      string
+	schedules string
+	watches   string
 }
 
 func NewJSON(root string) Store {
-	return &jsonStore{root: root, file: filepath.Join(root, "servers.json")}
+	return &jsonStore{
+		root:      root,
+		file:      filepath.Join(root, "servers.json"),
+		schedules: filepath.Join(root, "schedules.json"),
+		watches:   filepath.Join(root, "watches.json"),
+	}
 }
 
 func (s *jsonStore) LoadAll() ([]manager.ServerConfig, error) {
-	b, err := os.ReadFile(s.file)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []manager.ServerConfig{}, nil
-		}
+	arr := []manager.ServerConfig{}
+	if err := readJSON(s.file, &arr); err != nil {
 		return nil, err
 	}
-	var arr []manager.ServerConfig
-	if err := json.Unmarshal(b, &arr); err != nil {
+	return arr, nil
+}
+
+func (s *jsonStore) SaveAll(arr []manager.ServerConfig) error {
+	return writeJSON(s.file, arr)
+}
+
+func (s *jsonStore) LoadSchedules() ([]manager.Schedule, error) {
+	arr := []manager.Schedule{}
+	if err := readJSON(s.schedules, &arr); err != nil {
 		return nil, err
 	}
 	return arr, nil
 }
 
-func (s *jsonStore) SaveAll(arr []manager.ServerConfig) error {
-	b, err := json.MarshalIndent(arr, "", "  ")
+func (s *jsonStore) SaveSchedules(arr []manager.Schedule) error {
+	return writeJSON(s.schedules, arr)
+}
+
+func (s *jsonStore) LoadWatches() ([]manager.WatchedServer, error) {
+	arr := []manager.WatchedServer{}
+	if err := readJSON(s.watches, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+func (s *jsonStore) SaveWatches(arr []manager.WatchedServer) error {
+	return writeJSON(s.watches, arr)
+}
+
+// readJSON decodes file into out; a missing file leaves out untouched.
+func readJSON(file string, out any) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// writeJSON atomically replaces file with the indented JSON encoding of v.
+func writeJSON(file string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
-	tmp := s.file + ".tmp"
-	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+	// A unique temp file per write, so concurrent writers never share one
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
 		return err
 	}
-	return os.Rename(tmp, s.file)
+	tmp := f.Name()
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
 }
@@ -5,4 +5,8 @@ import "obsidian/internal/manager"
 type Store interface {
 	LoadAll() ([]manager.ServerConfig, error)
 	SaveAll([]manager.ServerConfig) error
+	LoadSchedules() ([]manager.Schedule, error)
+	SaveSchedules([]manager.Schedule) error
+	LoadWatches() ([]manager.WatchedServer, error)
+	SaveWatches([]manager.WatchedServer) error
 }
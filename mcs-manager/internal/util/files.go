@@ -1,8 +1,10 @@
 package util
 
 import (
+	"io"
 	"os"
 	"strings"
+	"time"
 )
 
 func TailFile(path string, lines int) (string, error) {
@@ -16,3 +18,37 @@ func TailFile(path string, lines int) (string, error) {
 	}
 	return strings.Join(arr, "\n"), nil
 }
+
+// FollowReader reads a file that is still being appended to, like "tail -f".
+// Reads block until new data arrives; once done is closed the remaining data
+// is drained and io.EOF is returned.
+type FollowReader struct {
+	f    *os.File
+	done <-chan struct{}
+}
+
+func NewFollowReader(f *os.File, done <-chan struct{}) *FollowReader {
+	return &FollowReader{f: f, done: done}
+}
+
+func (r *FollowReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		select {
+		case <-r.done:
+			if n, _ := r.f.Read(p); n > 0 {
+				return n, nil
+			}
+			return 0, io.EOF
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (r *FollowReader) Close() error { return r.f.Close() }
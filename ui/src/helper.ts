@@ -18,8 +18,35 @@
 import type { ServerInfo, CreateServerRequest, LogEvent } from "./types";
 
 const API_URL = "http://localhost:8484";
+const TOKEN_KEY = "mcs.token";
 let globalEventSource: EventSource | null = null;
 
+/**
+ * API token used for all requests, asked for once and kept in localStorage
+ */
+export function getToken(): string {
+  let token = localStorage.getItem(TOKEN_KEY) || "";
+  if (!token) {
+    token = window.prompt("API token")?.trim() || "";
+    if (token) localStorage.setItem(TOKEN_KEY, token);
+  }
+  return token;
+}
+
+/**
+ * Headers that authenticate a request against the API
+ */
+export function authHeaders(): Record<string, string> {
+  return { Authorization: `Bearer ${getToken()}` };
+}
+
+/**
+ * EventSource cannot send headers, so the token goes into the query string
+ */
+function eventsURL(): string {
+  return `${API_URL}/events?token=${encodeURIComponent(getToken())}`;
+}
+
 /**
  * Make an API request
  */
@@ -29,14 +56,17 @@ async function apiRequest<T = any>(
   body?: any
 ): Promise<T> {
   const url = `${API_URL}${path}`;
-  const options: RequestInit = { method };
+  const options: RequestInit = { method, headers: authHeaders() };
 
   if (body) {
-    options.headers = { "Content-Type": "application/json" };
+    options.headers = { ...authHeaders(), "Content-Type": "application/json" };
     options.body = JSON.stringify(body);
   }
 
   const response = await fetch(url, options);
+  if (response.status === 401) {
+    localStorage.removeItem(TOKEN_KEY);
+  }
   if (!response.ok) {
     throw new Error(`API Error: ${response.status} ${response.statusText}`);
   }
@@ -93,10 +123,10 @@ export async function restartServer(id: string): Promise<ServerInfo> {
 }
 
 /**
- * Delete a server (must be stopped)
+ * Delete a server (must be stopped). A final backup is kept unless force is set.
  */
-export async function deleteServer(id: string): Promise<void> {
-  return apiRequest(`/servers/${id}`, "DELETE");
+export async function deleteServer(id: string, force = false): Promise<void> {
+  return apiRequest(`/servers/${id}${force ? "?force=1" : ""}`, "DELETE");
 }
 
 /**
@@ -132,7 +162,7 @@ const serverListeners = new Map<string, Set<(info: ServerInfo) => void>>();
 function ensureGlobalEventSource() {
   if (globalEventSource) return;
 
-  globalEventSource = new EventSource(`${API_URL}/events`);
+  globalEventSource = new EventSource(eventsURL());
 
   // Handle server info updates (real-time player counts, state changes)
   globalEventSource.addEventListener("server.info", (event: Event) => {
@@ -241,7 +271,7 @@ export function subscribeToServerEvents(
     onError?: (error: Event) => void;
   }
 ): EventSource {
-  const eventSource = new EventSource(`${API_URL}/events`);
+  const eventSource = new EventSource(eventsURL());
 
   console.log("[subscribeToServerEvents] Connected for server:", serverId);
 
@@ -336,7 +366,7 @@ export function subscribeToLogs(
   onMessage?: (event: LogEvent) => void,
   onError?: (error: Event) => void
 ): EventSource {
-  const eventSource = new EventSource(`${API_URL}/events`);
+  const eventSource = new EventSource(eventsURL());
 
   eventSource.onmessage = (event) => {
     try {
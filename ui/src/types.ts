@@ -8,6 +8,19 @@ export interface ServerConfig {
   path: string;
   eula: boolean;
   jarUrl?: string;
+  minMemoryMb?: number;
+  jvmPreset?: "" | "aikar" | "zgc";
+  jvmArgs?: string[];
+  serverArgs?: string[];
+  env?: Record<string, string>;
+  hooks?: {
+    preStart?: string;
+    postStop?: string;
+    timeoutSec?: number;
+  };
+  cpuMax?: number;
+  memoryMaxMb?: number;
+  ioWeight?: number;
 }
 
 export type ServerState = "stopped" | "running" | "starting" | "crashed";
@@ -15,6 +28,7 @@ export type ServerState = "stopped" | "running" | "starting" | "crashed";
 export interface PlayerInfo {
   current: number;
   max: number;
+  names?: string[];
 }
 
 export interface ServerInfo {
@@ -24,6 +38,16 @@ export interface ServerInfo {
   uptimeSec: number;
   lastExitErr: string;
   players?: PlayerInfo;
+  commandLine?: string[];
+  limits?: CgroupLimits;
+}
+
+export interface CgroupLimits {
+  cgroup?: string;
+  cpuMax?: number;
+  memoryMaxMb?: number;
+  ioWeight?: number;
+  error?: string;
 }
 
 export interface CreateServerRequest {
@@ -39,7 +63,7 @@ export interface LogEvent {
   type: string;
   serverId: string;
   data?: {
-    stream: "stdout" | "stderr";
+    stream: "stdout" | "stderr" | "hook";
     line: string;
   };
 }